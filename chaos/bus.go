@@ -0,0 +1,252 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chaos provides a wire bus that injects network faults for testing
+// the clients under adverse conditions.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+const deliveryTimeout = time.Minute
+
+// Config configures the faults injected by the bus. Probabilities are in
+// [0, 1] and apply to each message independently.
+type Config struct {
+	Seed int64 // Seed of the random decisions, so that runs can be reproduced.
+
+	Drop      float64 // Probability that a message is lost.
+	Duplicate float64 // Probability that a message is delivered twice.
+	Reorder   float64 // Probability that a message is held back until the next one to the same recipient.
+	Corrupt   float64 // Probability that a bit of the encoded message is flipped.
+
+	MinDelay time.Duration // Lower bound of the delivery delay.
+	MaxDelay time.Duration // Upper bound of the delivery delay, also bounds holding back reordered messages.
+
+	Log bool // Log every injected fault.
+}
+
+// Stats counts the injected faults.
+type Stats struct {
+	Published  int
+	Dropped    int
+	Duplicated int
+	Reordered  int
+	Corrupted  int
+	Delayed    int
+}
+
+// Bus is a wire.Bus that forwards messages to another bus and injects faults.
+// All random decisions are made in the order in which messages are published,
+// so a run with the same seed and message order injects the same faults.
+type Bus struct {
+	bus wire.Bus
+	cfg Config
+
+	mu          sync.Mutex
+	rng         *rand.Rand
+	partitioned map[wire.AddrKey]bool
+	held        map[wire.AddrKey]*held // Reordered message per recipient.
+	stats       Stats
+}
+
+// held is a message held back to be delivered after the next one.
+type held struct {
+	env   *wire.Envelope
+	timer *time.Timer
+}
+
+var _ wire.Bus = (*Bus)(nil)
+
+// New creates a fault-injecting bus wrapping bus.
+func New(bus wire.Bus, cfg Config) *Bus {
+	if cfg.MaxDelay < cfg.MinDelay {
+		cfg.MaxDelay = cfg.MinDelay
+	}
+	return &Bus{
+		bus:         bus,
+		cfg:         cfg,
+		rng:         rand.New(rand.NewSource(cfg.Seed)), //nolint:gosec // Reproducibility is wanted.
+		partitioned: make(map[wire.AddrKey]bool),
+		held:        make(map[wire.AddrKey]*held),
+	}
+}
+
+// SubscribeClient implements wire.Bus.
+func (b *Bus) SubscribeClient(c wire.Consumer, addr map[wallet.BackendID]wire.Address) error {
+	return b.bus.SubscribeClient(c, addr)
+}
+
+// Partition cuts off the peer: all messages from or to it are lost until the
+// partition is healed.
+func (b *Bus) Partition(peer map[wallet.BackendID]wire.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.partitioned[wire.Keys(peer)] = true
+}
+
+// Heal ends the partition of the peer.
+func (b *Bus) Heal(peer map[wallet.BackendID]wire.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.partitioned, wire.Keys(peer))
+}
+
+// Stats returns the number of injected faults so far.
+func (b *Bus) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stats
+}
+
+// Publish implements wire.Bus. Lost messages are reported as sent, like on a
+// real network.
+func (b *Bus) Publish(ctx context.Context, env *wire.Envelope) error {
+	b.mu.Lock()
+	b.stats.Published++
+	recipient := wire.Keys(env.Recipient)
+	if b.partitioned[wire.Keys(env.Sender)] || b.partitioned[recipient] {
+		b.stats.Dropped++
+		b.mu.Unlock()
+		b.logf("partition drops %T", env.Msg)
+		return nil
+	}
+
+	// Draw all decisions in a fixed order to stay reproducible.
+	drop := b.rng.Float64() < b.cfg.Drop
+	corrupt := b.rng.Float64() < b.cfg.Corrupt
+	corruptPos := b.rng.Int()
+	duplicate := b.rng.Float64() < b.cfg.Duplicate
+	reorder := b.rng.Float64() < b.cfg.Reorder
+	delay := b.cfg.MinDelay
+	if span := b.cfg.MaxDelay - b.cfg.MinDelay; span > 0 {
+		delay += time.Duration(b.rng.Int63n(int64(span)))
+	}
+
+	if drop {
+		b.stats.Dropped++
+		b.mu.Unlock()
+		b.logf("drops %T", env.Msg)
+		return nil
+	}
+	if corrupt {
+		b.stats.Corrupted++
+		var ok bool
+		if env, ok = corrupted(env, corruptPos); !ok {
+			// The recipient could not decode the message and discards it.
+			b.mu.Unlock()
+			b.logf("corrupts and drops message")
+			return nil
+		}
+		b.logf("corrupts %T", env.Msg)
+	}
+	if duplicate {
+		b.stats.Duplicated++
+	}
+	if delay > 0 {
+		b.stats.Delayed++
+	}
+
+	// A held back message is delivered after this one.
+	prev := b.held[recipient]
+	if prev != nil && prev.timer.Stop() {
+		delete(b.held, recipient)
+	} else {
+		prev = nil
+	}
+	if reorder && prev == nil {
+		b.stats.Reordered++
+		h := &held{env: env}
+		h.timer = time.AfterFunc(b.cfg.MaxDelay, func() {
+			b.mu.Lock()
+			if b.held[recipient] == h {
+				delete(b.held, recipient)
+			}
+			b.mu.Unlock()
+			b.deliver(h.env)
+		})
+		b.held[recipient] = h
+		b.mu.Unlock()
+		b.logf("holds back %T", env.Msg)
+		return nil
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		err := b.bus.Publish(ctx, env)
+		b.delivered(env, duplicate, prev)
+		return err
+	}
+	go func() {
+		time.Sleep(delay)
+		b.deliver(env)
+		b.delivered(env, duplicate, prev)
+	}()
+	return nil
+}
+
+// delivered delivers the duplicate of a message and the message held back
+// before it.
+func (b *Bus) delivered(env *wire.Envelope, duplicate bool, prev *held) {
+	if duplicate {
+		b.logf("duplicates %T", env.Msg)
+		b.deliver(env)
+	}
+	if prev != nil {
+		b.deliver(prev.env)
+	}
+}
+
+// deliver forwards a message independently of the publisher's context.
+func (b *Bus) deliver(env *wire.Envelope) {
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	if err := b.bus.Publish(ctx, env); err != nil {
+		log.Printf("Chaos bus: delivering %T failed: %v", env.Msg, err)
+	}
+}
+
+func (b *Bus) logf(format string, args ...interface{}) {
+	if b.cfg.Log {
+		log.Printf("Chaos bus: "+format, args...)
+	}
+}
+
+// corrupted returns a copy of the envelope whose encoded message has a bit
+// flipped at the given position. It returns false if the result cannot be
+// decoded.
+func corrupted(env *wire.Envelope, pos int) (*wire.Envelope, bool) {
+	var buf bytes.Buffer
+	if err := wire.EncodeMsg(env.Msg, &buf); err != nil || buf.Len() == 0 {
+		return nil, false
+	}
+	data := buf.Bytes()
+	bit := pos % (len(data) * 8)
+	data[bit/8] ^= 1 << (bit % 8)
+
+	msg, err := wire.DecodeMsg(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	return &wire.Envelope{Sender: env.Sender, Recipient: env.Recipient, Msg: msg}, true
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// ErrSettlementUnsupported is returned for on-chain registering and
+// withdrawing on a chain whose adjudicator does not implement them.
+var ErrSettlementUnsupported = errors.New("settlement not supported")
+
+// subscribeOnlyAdjudicator is an adjudicator of which only Subscribe and
+// Progress may be used. The Solana adjudicator of the pinned backend panics
+// on Register and Withdraw, which would take down the whole process.
+type subscribeOnlyAdjudicator struct {
+	channel.Adjudicator
+	backend wallet.BackendID
+}
+
+// NewSubscribeOnlyAdjudicator wraps the adjudicator of the given chain so that
+// Register and Withdraw return ErrSettlementUnsupported instead of calling it.
+func NewSubscribeOnlyAdjudicator(adj channel.Adjudicator, backend wallet.BackendID) channel.Adjudicator {
+	return &subscribeOnlyAdjudicator{Adjudicator: adj, backend: backend}
+}
+
+// Register implements channel.Adjudicator.
+func (a *subscribeOnlyAdjudicator) Register(context.Context, channel.AdjudicatorReq, []channel.SignedState) error {
+	return fmt.Errorf("registering on chain %d: %w", a.backend, ErrSettlementUnsupported)
+}
+
+// Withdraw implements channel.Adjudicator.
+func (a *subscribeOnlyAdjudicator) Withdraw(context.Context, channel.AdjudicatorReq, channel.StateMap) error {
+	return fmt.Errorf("withdrawing on chain %d: %w", a.backend, ErrSettlementUnsupported)
+}
+
+// settleable are the chains on which channels can be registered and
+// withdrawn from. The Solana backend does not implement it yet.
+var settleable = map[wallet.BackendID]bool{1: true}
+
+// checkSettleable returns an error if any of the assets is on a chain on
+// which the channel cannot be settled, before anything is sent on the others.
+func checkSettleable(assets []channel.Asset) error {
+	for _, asset := range assets {
+		if b := backendOf(asset); !settleable[b] {
+			return fmt.Errorf("settling on chain %d: %w", b, ErrSettlementUnsupported)
+		}
+	}
+	return nil
+}
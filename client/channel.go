@@ -2,17 +2,22 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"time"
 
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
 )
 
 // PaymentChannel is a wrapper for a Perun channel for the payment use case.
 type PaymentChannel struct {
 	ch         *client.Channel
 	currencies []channel.Asset
+	events     *eventHub
+	client     *PaymentClient
 }
 
 func (c *PaymentChannel) GetChannel() *client.Channel {
@@ -27,10 +32,12 @@ func (c *PaymentChannel) GetChannelState() *channel.State {
 }
 
 // newPaymentChannel creates a new payment channel.
-func newPaymentChannel(ch *client.Channel, currencies []channel.Asset) *PaymentChannel {
+func newPaymentChannel(ch *client.Channel, c *PaymentClient) *PaymentChannel {
 	return &PaymentChannel{
 		ch:         ch,
-		currencies: currencies,
+		currencies: c.currency,
+		events:     c.events,
+		client:     c,
 	}
 }
 
@@ -53,58 +60,85 @@ func (c PaymentChannel) PerformSwap() {
 	}
 }
 
-// SendPayment sends a payment to the channel peer.
+// SendEthPayment sends a payment to the channel peer.
 func (c PaymentChannel) SendEthPayment(amount float64) {
-	// Transfer the given amount from us to peer.
-	// Use UpdateBy to update the channel state.
+	c.SendEthPaymentTo(c.onlyPeer(), amount)
+}
+
+// SendSolanaPayment sends a payment to the channel peer.
+func (c PaymentChannel) SendSolanaPayment(amount int64) {
+	c.SendSolanaPaymentTo(c.onlyPeer(), amount)
+}
+
+// SendEthPaymentTo sends a payment to the participant with the given index.
+func (c PaymentChannel) SendEthPaymentTo(peer channel.Index, amount float64) {
+	c.sendPayment(peer, c.currencies[0], EthToWei(big.NewFloat(amount)))
+}
+
+// SendSolanaPaymentTo sends a payment to the participant with the given index.
+func (c PaymentChannel) SendSolanaPaymentTo(peer channel.Index, amount int64) {
+	c.sendPayment(peer, c.currencies[1], big.NewInt(amount))
+}
+
+// sendPayment transfers the given amount of the asset from us to peer.
+func (c PaymentChannel) sendPayment(peer channel.Index, asset channel.Asset, amount *big.Int) {
+	actor := c.ch.Idx()
+	if peer == actor || int(peer) >= len(c.ch.Params().Parts) {
+		panic(fmt.Sprintf("invalid payee index: %d", peer))
+	}
+
+	// Use Update to update the channel state.
 	err := c.ch.Update(context.TODO(), func(state *channel.State) { // We use context.TODO to keep the code simple.
-		ethAmount := EthToWei(big.NewFloat(amount))
-		actor := c.ch.Idx()
-		peer := 1 - actor
-		state.Allocation.TransferBalance(actor, peer, c.currencies[0], ethAmount)
+		state.Allocation.TransferBalance(actor, peer, asset, amount)
 	})
 	if err != nil {
 		panic(err) // We panic on error to keep the code simple.
 	}
 }
 
-// SendPayment sends a payment to the channel peer.
-func (c PaymentChannel) SendSolanaPayment(amount int64) {
-	// Transfer the given amount from us to peer.
-	// Use UpdateBy to update the channel state.
-	err := c.ch.Update(context.TODO(), func(state *channel.State) {
-		icp := big.NewInt(amount)
-		actor := c.ch.Idx()
-		peer := 1 - actor
-		state.Allocation.TransferBalance(actor, peer, c.currencies[1], icp)
-	})
-	if err != nil {
-		panic(err)
+// PeerIndex returns the channel index of the participant with the given wire
+// address.
+func (c PaymentChannel) PeerIndex(addr map[wallet.BackendID]wire.Address) (channel.Index, bool) {
+	for i, peer := range c.ch.Peers() {
+		if channel.EqualWireMaps(peer, addr) {
+			return channel.Index(i), true
+		}
 	}
+	return 0, false
+}
+
+// onlyPeer returns the index of the other participant of a two-party channel.
+// In channels with more participants the payee must be given explicitly.
+func (c PaymentChannel) onlyPeer() channel.Index {
+	if n := len(c.ch.Params().Parts); n != 2 {
+		panic(fmt.Sprintf("ambiguous payee in channel with %d participants", n))
+	}
+	return 1 - c.ch.Idx()
 }
 
 // Settle settles the payment channel and withdraws the funds.
 func (c PaymentChannel) Settle() {
 	// Finalize the channel to enable fast settlement.
-	if !c.ch.State().IsFinal {
-		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Second)
-		defer cancel()
-		err := c.ch.Update(ctx, func(state *channel.State) {
-			state.IsFinal = true
-		})
-		if err != nil {
-			panic(err)
-		}
+	if err := c.finalize(); err != nil {
+		panic(err)
 	}
 
 	// Settle concludes the channel and withdraws the funds.
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
-	err := c.ch.Settle(ctx, false)
-	if err != nil {
+	if err := c.client.settle(ctx, c.ch, false); err != nil {
 		panic(err)
 	}
+}
 
-	// Close frees up channel resources.
-	c.ch.Close()
+// finalize makes the current channel state final if it is not yet.
+func (c PaymentChannel) finalize() error {
+	if c.ch.State().IsFinal {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Second)
+	defer cancel()
+	return c.ch.Update(ctx, func(state *channel.State) {
+		state.IsFinal = true
+	})
 }
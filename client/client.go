@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/gagliardetto/solana-go/rpc"
 	ethchannel "github.com/perun-network/perun-eth-backend/channel"
 	ethwallet "github.com/perun-network/perun-eth-backend/wallet"
 	simplewallet "github.com/perun-network/perun-eth-backend/wallet/simple"
@@ -18,10 +21,15 @@ import (
 	"github.com/pkg/errors"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/channel/multi"
+	"perun.network/go-perun/channel/persistence/keyvalue"
 	"perun.network/go-perun/client"
 	"perun.network/go-perun/wallet"
 	"perun.network/go-perun/watcher/local"
 	"perun.network/go-perun/wire"
+	"perun.network/sol-eth-cross-chain-demo/history"
+	"perun.network/sol-eth-cross-chain-demo/oracle"
+	"perun.network/sol-eth-cross-chain-demo/webhook"
+	"polycry.pt/poly-go/sortedkv/leveldb"
 
 	solchannel "github.com/perun-network/perun-solana-backend/channel"
 	soladjudicator "github.com/perun-network/perun-solana-backend/channel/adjudicator"
@@ -35,7 +43,35 @@ type PaymentClient struct {
 	account     map[wallet.BackendID]wallet.Address // The account we use for on-chain and off-chain transactions.
 	waddress    map[wallet.BackendID]wire.Address
 	currency    []channel.Asset      // The currency we expect to get paid in.
-	channels    chan *PaymentChannel // Accepted payment channels.
+	channels    chan *PaymentChannel // Accepted ledger channels.
+	subChannels chan *PaymentChannel // Accepted sub-channels and virtual channels.
+	events      *eventHub            // Subscribers to channel events.
+	webhooks    atomic.Pointer[webhook.Dispatcher]
+
+	depositCheckers map[wallet.BackendID]depositChecker      // Deposit status per chain.
+	adjudicators    map[wallet.BackendID]channel.Adjudicator // Adjudicator per chain.
+	wallets         map[wallet.BackendID]wallet.Wallet
+	fundingLimits   map[wallet.BackendID]channel.Bal // Maximum deposit per chain into proposed channels.
+	funding         *fundingTracker                  // Funding progress of the channels.
+
+	mu           sync.Mutex
+	openChannels map[channel.ID]*client.Channel // Channels not yet settled by us.
+	streams      map[channel.ID]*streamCheck    // Expected payment streams.
+
+	router   *msgRouter     // Messages of our own protocols.
+	invoices *invoiceLedger // Invoices we issued and received.
+	hub      *hub           // Payments forwarded between our channels.
+	orders   *orderBook     // Our orders and those of our peers.
+	oracle   oracle.Oracle  // Prices for valuing swaps, nil without.
+	slippage *big.Rat       // Tolerated shortfall of a swap's value.
+
+	gas         GasConfig         // Gas limits and fees of our Ethereum transactions.
+	gasPrices   gasPriceSuggester // Node suggesting gas prices without a fee strategy.
+	solanaTxFee uint64            // Estimated fee per Solana transaction in lamports.
+
+	stopBumps context.CancelFunc        // Stops resubmitting our Ethereum transactions.
+	watcher   *recordingWatcher         // Latest signed states of the watched channels.
+	persister *keyvalue.PersistRestorer // Channel database, nil without persistence.
 }
 
 // SetupPaymentClient creates a new payment client.
@@ -54,10 +90,29 @@ func SetupPaymentClient(
 	solAsset channel.Asset,
 	solFunder *solfunder.Funder,
 	solAdj *soladjudicator.Adjudicator,
+	opts ...Option,
 ) (*PaymentClient, error) {
+	o := makeOptions(opts)
+	if o.hub != nil {
+		if err := o.hub.checkRoutes(); err != nil {
+			return nil, err
+		}
+	}
 	multiAdjudicator := multi.NewAdjudicator()
-	watcher, err := local.NewWatcher(multiAdjudicator)
+	localWatcher, err := local.NewWatcher(multiAdjudicator)
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating watcher")
+	}
+	var hist *history.Store
+	if o.historyDir != "" {
+		if hist, err = history.NewStore(o.historyDir); err != nil {
+			return nil, err
+		}
+	}
+	watcher := newRecordingWatcher(localWatcher, hist)
 	multiFunder := multi.NewFunder()
+	events := newEventHub()
+	funding := newFundingTracker(events)
 	ccWallet := map[wallet.BackendID]wallet.Wallet{1: ethWallet, 6: solWallet}
 
 	solPart, ok := solAccount.Address().(*solwallet.Participant)
@@ -66,7 +121,15 @@ func SetupPaymentClient(
 	}
 
 	// Create Ethereum client and contract backend.
-	cb, err := CreateContractBackend(nodeURL, chainID, ethWallet)
+	o.finality = o.finality.withDefaults()
+	bumpCtx, stopBumps := context.WithCancel(context.Background())
+	started := false
+	defer func() {
+		if !started {
+			stopBumps()
+		}
+	}()
+	cb, err := createContractBackend(bumpCtx, nodeURL, chainID, ethWallet, o.gas, o.finality.EthConfirmations)
 	if err != nil {
 		return nil, fmt.Errorf("creating contract backend: %w", err)
 	}
@@ -85,27 +148,56 @@ func SetupPaymentClient(
 	ethFunder := ethchannel.NewFunder(cb)
 	ethAssetID := ethchannel.MakeLedgerBackendID(big.NewInt(int64(chainID)))
 	solAssetID := solchannel.MakeCCID(solchannel.MakeContractID("6"))
-	multiFunder.RegisterFunder(ethAssetID, ethFunder)
-	multiFunder.RegisterFunder(solAssetID, solFunder)
+	depositCheckers := map[wallet.BackendID]depositChecker{
+		1: &ethDepositChecker{cb: cb, assetHolder: common.Address(assetAddr), confirmations: o.finality.EthConfirmations},
+		6: &solDepositChecker{rpcClient: rpc.New(o.finality.SolanaRPC), perunAddr: solFunder.GetPerunAddr(), commitment: o.finality.SolanaCommitment},
+	}
+	for _, f := range []struct {
+		id      multi.LedgerBackendID
+		backend wallet.BackendID
+		funder  channel.Funder
+	}{{ethAssetID, 1, ethFunder}, {solAssetID, 6, solFunder}} {
+		final := &finalityFunder{Funder: f.funder, checker: depositCheckers[f.backend], backend: f.backend, interval: o.finality.PollInterval}
+		tracked := &trackingFunder{
+			Funder:   final,
+			backend:  f.backend,
+			checker:  depositCheckers[f.backend],
+			tracker:  funding,
+			interval: o.finality.PollInterval,
+			timeout:  o.fundingTimeout.of(f.backend),
+		}
+		multiFunder.RegisterFunder(f.id, &notifyingFunder{Funder: tracked, backend: f.backend, events: events})
+	}
 
-	dep := ethchannel.NewETHDepositor(50000)
+	dep := ethchannel.NewETHDepositor(o.gas.limit(GasDeposit, 50000))
 	ethAcc := accounts.Account{Address: acc}
 	ethAsset := ethchannel.NewAsset(big.NewInt(int64(chainID)), common.Address(assetAddr))
 	ethFunder.RegisterAsset(*ethAsset, dep, ethAcc)
 
 	// Setup adjudicator.
-	ethAdj := ethchannel.NewAdjudicator(cb, adjudicator, acc, ethAcc, 1000000)
+	ethAdj := ethchannel.NewAdjudicator(cb, adjudicator, acc, ethAcc, o.gas.limit(GasRegister, 1000000))
 	multiAdjudicator.RegisterAdjudicator(ethAssetID, ethAdj)
-	multiAdjudicator.RegisterAdjudicator(solAssetID, solAdj)
+	solSubAdj := NewSubscribeOnlyAdjudicator(solAdj, 6)
+	multiAdjudicator.RegisterAdjudicator(solAssetID, solSubAdj)
 
 	// Setup Perun client.
 	solWireAddr := swire.NewAddress(solPart.String())
 	ethWireAddr := &ethwire.Address{Address: ethAddress}
 	addresses := map[wallet.BackendID]wire.Address{1: ethWireAddr, 6: solWireAddr}
-	perunClient, err := client.New(addresses, bus, multiFunder, multiAdjudicator, ccWallet, watcher)
+	router := newMsgRouter(bus)
+	perunClient, err := client.New(addresses, router, multiFunder, multiAdjudicator, ccWallet, watcher)
 	if err != nil {
 		return nil, errors.WithMessage(err, "creating client")
 	}
+	var persister *keyvalue.PersistRestorer
+	if o.persistenceDir != "" {
+		db, err := leveldb.LoadDatabase(o.persistenceDir)
+		if err != nil {
+			return nil, errors.WithMessage(err, "opening channel database")
+		}
+		persister = keyvalue.NewPersistRestorer(db)
+		perunClient.EnablePersistence(persister)
+	}
 
 	// Setup Accounts
 	account := map[wallet.BackendID]wallet.Address{1: ethAddress, 6: solAccount.Address()}
@@ -116,61 +208,89 @@ func SetupPaymentClient(
 		account:     account,
 		waddress:    addresses,
 		currency:    []channel.Asset{ethAsset, solAsset},
-		channels:    make(chan *PaymentChannel, 1),
+		channels:    make(chan *PaymentChannel, acceptQueueSize),
+		subChannels: make(chan *PaymentChannel, acceptQueueSize),
+		events:      events,
+
+		depositCheckers: depositCheckers,
+		adjudicators:    map[wallet.BackendID]channel.Adjudicator{1: ethAdj, 6: solSubAdj},
+		wallets:         ccWallet,
+		fundingLimits:   o.fundingLimits,
+		funding:         funding,
+		openChannels:    make(map[channel.ID]*client.Channel),
+		streams:         make(map[channel.ID]*streamCheck),
+		router:          router,
+		invoices:        newInvoiceLedger(),
+		hub:             newHub(o.hub),
+		orders:          newOrderBook(),
+		oracle:          o.oracle,
+		slippage:        new(big.Rat).SetFloat64(o.slippage),
+		gas:             o.gas,
+		gasPrices:       cb,
+		solanaTxFee:     o.solanaTxFee,
+		stopBumps:       stopBumps,
+		watcher:         watcher,
+		persister:       persister,
 	}
+	router.handle(invoiceMsgType, c.handleInvoice)
+	router.handle(invoicePaymentMsgType, c.handleInvoicePayment)
+	router.handle(forwardMsgType, c.handleForward)
+	router.handle(orderMsgType, c.handleOrder)
+	router.handle(orderCancelMsgType, c.handleOrderCancel)
+	router.handle(orderFillMsgType, c.handleOrderFill)
 	go perunClient.Handle(c, c)
 
+	started = true
 	return c, nil
 }
 
-// OpenChannel opens a new channel with the specified peer and funding.
+// OpenChannel opens a new channel with the specified peer and funding. We
+// deposit ethAmount ETH and the peer deposits solAmount lamports.
 func (c *PaymentClient) OpenChannel(peer map[wallet.BackendID]wire.Address, ethAmount float64, solAmount uint64) *PaymentChannel {
-	// We define the channel participants. The proposer has always index 0. Here
-	// we use the on-chain addresses as off-chain addresses, but we could also
-	// use different ones.
-	participants := []map[wallet.BackendID]wire.Address{c.waddress, peer}
-
-	// We create an initial allocation which defines the starting balances.
-	initAlloc := channel.NewAllocation(2, []wallet.BackendID{1, 6}, c.currency[0], c.currency[1])
 	log.Println("ETH amount: ", ethAmount, c.currency[0])
-
 	log.Println("SOL amount: ", solAmount, c.currency[1])
-	initAlloc.SetAssetBalances(c.currency[0], []channel.Bal{
-		EthToWei(big.NewFloat(ethAmount)), // Our initial balance.
-		big.NewInt(0),                     // Peer's initial balance.
-	})
-	initAlloc.SetAssetBalances(c.currency[1], []channel.Bal{
-		big.NewInt(0),                // Our initial balance.
-		big.NewInt(int64(solAmount)), // Peer's initial balance.
-	})
-
-	// Prepare the channel proposal by defining the channel parameters.
-	challengeDuration := uint64(1000) // On-chain challenge duration in seconds.
-	log.Println("Creating channel proposal")
-	proposal, err := client.NewLedgerChannelProposal(
-		challengeDuration,
-		c.account,
-		initAlloc,
-		participants,
-	)
+	ch, err := c.OpenChannelWith(context.TODO(), peer, swapChannelOptions(ethAmount, solAmount))
 	if err != nil {
 		panic(err)
 	}
+	return ch
+}
 
-	// Send the proposal.
-	log.Println("Sending channel proposal", proposal)
-	// ctx, cancel := context.WithTimeout(context.Background(), 180*time.Second)
-	// defer cancel()
-	ch, err := c.perunClient.ProposeChannel(context.TODO(), proposal)
-	if err != nil {
-		panic(err)
+// swapChannelOptions returns the options of a channel in which we deposit
+// ethAmount ETH and the peer deposits solAmount lamports.
+func swapChannelOptions(ethAmount float64, solAmount uint64) ChannelOptions {
+	return ChannelOptions{
+		Balances: channel.Balances{
+			{EthToWei(big.NewFloat(ethAmount)), big.NewInt(0)}, // Our and the peer's initial ETH balance.
+			{big.NewInt(0), big.NewInt(int64(solAmount))},      // Our and the peer's initial SOL balance.
+		},
 	}
+}
+
+// openedChannel starts watching the newly opened channel, subscribes to its
+// updates and wraps it as a payment channel.
+func (c *PaymentClient) openedChannel(ch *client.Channel) *PaymentChannel {
+	c.mu.Lock()
+	c.openChannels[ch.ID()] = ch
+	c.mu.Unlock()
 
 	// Start the on-chain event watcher. It automatically handles disputes.
-	log.Println("Starting dispute watcher", ch.ID())
 	c.startWatching(ch)
 
-	return newPaymentChannel(ch, c.currency)
+	// Forward all state transitions to the event subscribers.
+	ch.OnUpdate(c.events.publishUpdate)
+	c.events.publish(Event{
+		Type:      ChannelOpened,
+		ChannelID: ch.ID(),
+		Version:   ch.State().Version,
+		New:       allocationOf(ch.State()),
+	})
+	c.notify(webhook.ChannelOpened, ch.ID(), map[string]interface{}{
+		"version": ch.State().Version,
+		"index":   ch.Idx(),
+	})
+
+	return newPaymentChannel(ch, c)
 }
 
 // startWatching starts the dispute watcher for the specified channel.
@@ -183,15 +303,94 @@ func (c *PaymentClient) startWatching(ch *client.Channel) {
 	}()
 }
 
-// AcceptedChannel returns the next accepted channel.
+// settle concludes the channel, withdraws our funds and closes it. If
+// secondary is set, the peer is expected to conclude the channel.
+func (c *PaymentClient) settle(ctx context.Context, ch *client.Channel, secondary bool) error {
+	if err := checkSettleable(ch.State().Assets); err != nil {
+		return err
+	}
+	if err := ch.Settle(ctx, secondary); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.openChannels, ch.ID())
+	delete(c.streams, ch.ID())
+	c.mu.Unlock()
+	c.events.publish(Event{
+		Type:      Withdrawn,
+		ChannelID: ch.ID(),
+		Version:   ch.State().Version,
+		New:       allocationOf(ch.State()),
+	})
+
+	// Close frees up channel resources.
+	ch.Close()
+	return nil
+}
+
+// AcceptedChannel returns the next accepted ledger channel.
 func (c *PaymentClient) AcceptedChannel() *PaymentChannel {
 	log.Println("Waiting for accepted channel", c.channels)
 	return <-c.channels
 }
 
-// Shutdown gracefully shuts down the client.
+// AcceptedSubChannel returns the next accepted sub-channel or virtual
+// channel.
+func (c *PaymentClient) AcceptedSubChannel() *PaymentChannel {
+	return <-c.subChannels
+}
+
+// queueAccepted queues an accepted channel for AcceptedChannel or
+// AcceptedSubChannel. If the queue is full because nobody takes the
+// channels, e.g. in hub mode, the channel is not queued; it is open and
+// watched nevertheless.
+func (c *PaymentClient) queueAccepted(ch *PaymentChannel) {
+	queue := c.channels
+	if ch.ch.Parent() != nil {
+		queue = c.subChannels
+	}
+	select {
+	case queue <- ch:
+	default:
+		log.Printf("Accepted channel %x not queued, queue is full", ch.ch.ID())
+	}
+}
+
+// Restore restores the channels stored in the channel database, resumes
+// watching them and returns them.
+func (c *PaymentClient) Restore(ctx context.Context) ([]*PaymentChannel, error) {
+	if c.persister == nil {
+		return nil, errors.New("persistence not enabled")
+	}
+
+	var mu sync.Mutex
+	var restored []*PaymentChannel
+	c.perunClient.OnNewChannel(func(ch *client.Channel) {
+		pch := c.openedChannel(ch)
+		mu.Lock()
+		defer mu.Unlock()
+		restored = append(restored, pch)
+	})
+	defer c.perunClient.OnNewChannel(func(*client.Channel) {})
+
+	if err := c.perunClient.Restore(ctx); err != nil {
+		return restored, errors.WithMessage(err, "restoring channels")
+	}
+	return restored, nil
+}
+
+// Shutdown shuts down the client immediately, leaving all open channels
+// unwatched. See GracefulShutdown for handling the open channels first.
 func (c *PaymentClient) Shutdown() {
 	c.perunClient.Close()
+	c.stopBumps()
+	c.hub.stop()
+	if c.persister != nil {
+		if err := c.persister.Close(); err != nil {
+			log.Printf("Closing channel database failed: %v", err)
+		}
+	}
+	c.events.close()
 }
 
 func (c *PaymentClient) Addresses() map[wallet.BackendID]wallet.Address {
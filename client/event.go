@@ -0,0 +1,314 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+const (
+	eventQueueSize = 1024 // Number of undelivered events kept per subscriber.
+)
+
+// EventType is the type of a channel event.
+type EventType int
+
+// Channel event types emitted by the PaymentClient.
+const (
+	ProposalReceived  EventType = iota // A channel proposal was received from a peer.
+	ChannelOpened                      // A channel was opened and funded.
+	FundingCompleted                   // Our funding on one chain completed.
+	UpdateApplied                      // A channel update was applied.
+	Finalized                          // The channel state became final.
+	Disputed                           // A state was registered on-chain.
+	Concluded                          // The channel was concluded on-chain.
+	Withdrawn                          // Our funds were withdrawn from the channel.
+	FundingProgressed                  // A participant's deposit progressed.
+	InvoiceReceived                    // An invoice was received from a peer.
+	InvoiceSettled                     // An invoice was paid.
+)
+
+var eventTypeNames = map[EventType]string{
+	ProposalReceived:  "ProposalReceived",
+	ChannelOpened:     "ChannelOpened",
+	FundingCompleted:  "FundingCompleted",
+	UpdateApplied:     "UpdateApplied",
+	Finalized:         "Finalized",
+	Disputed:          "Disputed",
+	Concluded:         "Concluded",
+	Withdrawn:         "Withdrawn",
+	FundingProgressed: "FundingProgressed",
+	InvoiceReceived:   "InvoiceReceived",
+	InvoiceSettled:    "InvoiceSettled",
+}
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// Event is a channel event emitted to subscribers.
+type Event struct {
+	Type      EventType
+	Time      time.Time
+	ChannelID channel.ID       // Zero for ProposalReceived.
+	Version   uint64           // State version the event refers to.
+	Backend   wallet.BackendID // Chain of a funding event.
+	Old       *channel.Allocation
+	New       *channel.Allocation
+	Proposal  *ProposalInfo    // Set for ProposalReceived.
+	Funding   *FundingProgress // Set for FundingProgressed.
+	Invoice   *Invoice         // Set for invoice events.
+}
+
+// ProposalInfo describes a received channel proposal.
+type ProposalInfo struct {
+	ProposalID        [32]byte
+	ChallengeDuration uint64
+	NumPeers          int
+}
+
+// EventFilter decides whether a subscriber receives an event. A nil filter
+// matches all events.
+type EventFilter func(Event) bool
+
+// EventsOfType returns a filter that matches the given event types.
+func EventsOfType(types ...EventType) EventFilter {
+	return func(e Event) bool {
+		for _, t := range types {
+			if e.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// EventsOfChannel returns a filter that matches events of the given channel.
+func EventsOfChannel(id channel.ID) EventFilter {
+	return func(e Event) bool {
+		return e.ChannelID == id
+	}
+}
+
+// Subscribe returns a channel on which all events matching the filter are
+// delivered. Slow subscribers never block the client: if a subscriber falls
+// more than eventQueueSize events behind, the oldest events are dropped. The
+// channel is closed on Unsubscribe or Shutdown.
+func (c *PaymentClient) Subscribe(filter EventFilter) <-chan Event {
+	return c.events.subscribe(filter)
+}
+
+// Unsubscribe cancels the subscription that returned the given channel.
+func (c *PaymentClient) Unsubscribe(sub <-chan Event) {
+	c.events.unsubscribe(sub)
+}
+
+// eventHub fans out events to all subscribers.
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[<-chan Event]*eventSub
+	closed bool
+}
+
+// eventSub is a single subscription with its own delivery queue.
+type eventSub struct {
+	filter EventFilter
+	out    chan Event
+	mu     sync.Mutex
+	queue  []Event
+	notify chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[<-chan Event]*eventSub)}
+}
+
+func (h *eventHub) subscribe(filter EventFilter) <-chan Event {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &eventSub{
+		filter: filter,
+		out:    make(chan Event),
+		notify: make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(s.out)
+		return s.out
+	}
+	h.subs[s.out] = s
+	go s.deliver()
+	return s.out
+}
+
+func (h *eventHub) unsubscribe(sub <-chan Event) {
+	h.mu.Lock()
+	s, ok := h.subs[sub]
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	if ok {
+		s.cancel()
+	}
+}
+
+// publish enqueues the event for all matching subscribers. It never blocks.
+func (h *eventHub) publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.subs {
+		if s.filter == nil || s.filter(e) {
+			s.enqueue(e)
+		}
+	}
+}
+
+// close cancels all subscriptions.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for k, s := range h.subs {
+		s.cancel()
+		delete(h.subs, k)
+	}
+}
+
+func (s *eventSub) enqueue(e Event) {
+	s.mu.Lock()
+	if len(s.queue) >= eventQueueSize {
+		log.Printf("Event queue full, dropping %v event", s.queue[0].Type)
+		s.queue = s.queue[1:]
+	}
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliver forwards queued events to the subscriber until it is cancelled.
+func (s *eventSub) deliver() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.notify:
+				continue
+			case <-s.ctx.Done():
+				return
+			}
+		}
+		e := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- e:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// notifyingFunder wraps a ledger funder and emits a FundingCompleted event
+// once our deposit on that ledger is complete.
+type notifyingFunder struct {
+	channel.Funder
+	backend wallet.BackendID
+	events  *eventHub
+}
+
+// Fund implements channel.Funder.
+func (f *notifyingFunder) Fund(ctx context.Context, req channel.FundingReq) error {
+	if err := f.Funder.Fund(ctx, req); err != nil {
+		return err
+	}
+	f.events.publish(Event{
+		Type:      FundingCompleted,
+		ChannelID: req.Params.ID(),
+		Version:   req.State.Version,
+		Backend:   f.backend,
+		New:       allocationOf(req.State),
+	})
+	return nil
+}
+
+// publishUpdate emits the events for a state transition of a channel.
+func (h *eventHub) publishUpdate(from, to *channel.State) {
+	h.publish(Event{
+		Type:      UpdateApplied,
+		ChannelID: to.ID,
+		Version:   to.Version,
+		Old:       allocationOf(from),
+		New:       allocationOf(to),
+	})
+	if to.IsFinal && (from == nil || !from.IsFinal) {
+		h.publish(Event{
+			Type:      Finalized,
+			ChannelID: to.ID,
+			Version:   to.Version,
+			New:       allocationOf(to),
+		})
+	}
+}
+
+// publishAdjudicatorEvent emits the event corresponding to an on-chain event.
+func (h *eventHub) publishAdjudicatorEvent(e channel.AdjudicatorEvent) {
+	ev := Event{ChannelID: e.ID(), Version: e.Version()}
+	switch e := e.(type) {
+	case *channel.RegisteredEvent:
+		ev.Type = Disputed
+		ev.New = allocationOf(e.State)
+	case *channel.ProgressedEvent:
+		ev.Type = Disputed
+		ev.New = allocationOf(e.State)
+	case *channel.ConcludedEvent:
+		ev.Type = Concluded
+	default:
+		return
+	}
+	h.publish(ev)
+}
+
+func allocationOf(s *channel.State) *channel.Allocation {
+	if s == nil {
+		return nil
+	}
+	alloc := s.Allocation.Clone()
+	return &alloc
+}
@@ -0,0 +1,438 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/perun-network/perun-eth-backend/bindings/assetholdereth"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	solclient "github.com/perun-network/perun-solana-backend/client"
+	"github.com/perun-network/perun-solana-backend/encoding"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+const (
+	defaultFinalityPollInterval = 2 * time.Second
+)
+
+// ErrDepositReorged is returned when a deposit that was already observed
+// disappears from the chain before it became final.
+var ErrDepositReorged = errors.New("deposit dropped by chain reorganization")
+
+// FinalityConfig configures when deposits on each chain are considered final.
+type FinalityConfig struct {
+	EthConfirmations uint64             // Blocks a transaction must be included in.
+	SolanaCommitment rpc.CommitmentType // Commitment at which Solana deposits are final.
+	SolanaRPC        string             // Solana RPC endpoint used to check deposits, the one of the Solana setup.
+	PollInterval     time.Duration      // Interval between deposit checks.
+}
+
+// DefaultFinalityConfig returns the finality configuration used by default.
+func DefaultFinalityConfig() FinalityConfig {
+	return FinalityConfig{
+		EthConfirmations: txFinalityDepth,
+		SolanaCommitment: rpc.CommitmentFinalized,
+		SolanaRPC:        rpc.LocalNet_RPC,
+		PollInterval:     defaultFinalityPollInterval,
+	}
+}
+
+// withDefaults returns the configuration with unset fields set to their
+// default values.
+func (cfg FinalityConfig) withDefaults() FinalityConfig {
+	def := DefaultFinalityConfig()
+	if cfg.EthConfirmations == 0 {
+		cfg.EthConfirmations = def.EthConfirmations
+	}
+	if cfg.SolanaCommitment == "" {
+		cfg.SolanaCommitment = def.SolanaCommitment
+	}
+	if cfg.SolanaRPC == "" {
+		cfg.SolanaRPC = def.SolanaRPC
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	return cfg
+}
+
+// DepositStatus is the on-chain status of the deposits of an asset.
+type DepositStatus int
+
+// Deposit states.
+const (
+	DepositMissing DepositStatus = iota // Not all deposits are visible.
+	DepositPending                      // All deposits are visible but not final.
+	DepositFinal                        // All deposits are final.
+)
+
+// String returns the name of the deposit status.
+func (s DepositStatus) String() string {
+	switch s {
+	case DepositMissing:
+		return "missing"
+	case DepositPending:
+		return "pending"
+	case DepositFinal:
+		return "final"
+	default:
+		return "unknown"
+	}
+}
+
+// AssetFundingStatus is the funding status of one asset of a channel.
+type AssetFundingStatus struct {
+	Asset     channel.Asset
+	Backend   wallet.BackendID
+	Status    DepositStatus
+	Required  *big.Int // Total amount to be deposited.
+	Deposited *big.Int // Amount deposited at the final block, nil if unknown.
+}
+
+// FundingStatus returns the current funding status of all assets of the
+// channel with the given ID.
+func (c *PaymentClient) FundingStatus(ctx context.Context, id channel.ID) ([]AssetFundingStatus, error) {
+	ch, err := c.perunClient.Channel(id)
+	if err != nil {
+		return nil, fmt.Errorf("looking up channel: %w", err)
+	}
+
+	state := ch.State()
+	statuses := make([]AssetFundingStatus, 0, len(state.Assets))
+	for i, asset := range state.Assets {
+		checker, ok := c.depositCheckers[backendOf(asset)]
+		if !ok {
+			return nil, fmt.Errorf("no deposit checker for asset %d", i)
+		}
+		st, err := checker.status(ctx, ch.Params(), state, i)
+		if err != nil {
+			return nil, fmt.Errorf("checking asset %d: %w", i, err)
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// backendOf returns the wallet backend of an asset of the demo.
+func backendOf(asset channel.Asset) wallet.BackendID {
+	if _, ok := asset.(*ethchannel.Asset); ok {
+		return 1
+	}
+	return 6
+}
+
+// depositChecker checks the deposits of an asset on a single chain.
+type depositChecker interface {
+	status(ctx context.Context, params *channel.Params, state *channel.State, assetIdx int) (AssetFundingStatus, error)
+	// deposits returns the deposit of each participant.
+	deposits(ctx context.Context, params *channel.Params, state *channel.State, assetIdx int) ([]partDeposit, error)
+}
+
+// partDeposit is the deposit of a single participant.
+type partDeposit struct {
+	Status DepositStatus
+	TxID   string // Transaction of the deposit, if known.
+}
+
+// ethDepositChecker checks deposits in the ETH asset holder.
+type ethDepositChecker struct {
+	cb            ethchannel.ContractBackend
+	assetHolder   common.Address
+	confirmations uint64
+}
+
+func (e *ethDepositChecker) status(ctx context.Context, params *channel.Params, state *channel.State, assetIdx int) (AssetFundingStatus, error) {
+	st := AssetFundingStatus{
+		Asset:    state.Assets[assetIdx],
+		Backend:  1,
+		Required: state.Allocation.Sum()[assetIdx],
+	}
+
+	caller, err := assetholdereth.NewAssetholderethCaller(e.assetHolder, e.cb)
+	if err != nil {
+		return st, fmt.Errorf("binding asset holder: %w", err)
+	}
+	head, err := e.cb.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return st, fmt.Errorf("fetching head: %w", err)
+	}
+	confirmed := new(big.Int).Set(head.Number)
+	if e.confirmations > 1 {
+		confirmed.Sub(confirmed, new(big.Int).SetUint64(e.confirmations-1))
+	}
+
+	holdings := func(block *big.Int) (*big.Int, error) {
+		sum := new(big.Int)
+		for _, fid := range ethchannel.FundingIDs(params.ID(), params.Parts...) {
+			h, err := caller.Holdings(&bind.CallOpts{Context: ctx, BlockNumber: block}, fid)
+			if err != nil {
+				return nil, err
+			}
+			sum.Add(sum, h)
+		}
+		return sum, nil
+	}
+
+	latest, err := holdings(head.Number)
+	if err != nil {
+		return st, fmt.Errorf("reading holdings: %w", err)
+	}
+	final, err := holdings(confirmed)
+	if err != nil {
+		return st, fmt.Errorf("reading holdings: %w", err)
+	}
+	st.Deposited = final
+
+	switch {
+	case final.Cmp(st.Required) >= 0:
+		st.Status = DepositFinal
+	case latest.Cmp(st.Required) >= 0:
+		st.Status = DepositPending
+	}
+	return st, nil
+}
+
+func (e *ethDepositChecker) deposits(ctx context.Context, params *channel.Params, state *channel.State, assetIdx int) ([]partDeposit, error) {
+	caller, err := assetholdereth.NewAssetholderethCaller(e.assetHolder, e.cb)
+	if err != nil {
+		return nil, fmt.Errorf("binding asset holder: %w", err)
+	}
+	filterer, err := assetholdereth.NewAssetholderethFilterer(e.assetHolder, e.cb)
+	if err != nil {
+		return nil, fmt.Errorf("binding asset holder: %w", err)
+	}
+	head, err := e.cb.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching head: %w", err)
+	}
+	confirmed := new(big.Int).Set(head.Number)
+	if e.confirmations > 1 {
+		confirmed.Sub(confirmed, new(big.Int).SetUint64(e.confirmations-1))
+	}
+
+	fids := ethchannel.FundingIDs(params.ID(), params.Parts...)
+	deposits := make([]partDeposit, len(fids))
+	for i, fid := range fids {
+		need := state.Balances[assetIdx][i]
+		if need.Sign() == 0 {
+			deposits[i].Status = DepositFinal
+			continue
+		}
+		latest, err := caller.Holdings(&bind.CallOpts{Context: ctx, BlockNumber: head.Number}, fid)
+		if err != nil {
+			return nil, fmt.Errorf("reading holdings: %w", err)
+		}
+		if latest.Cmp(need) < 0 {
+			continue
+		}
+		final, err := caller.Holdings(&bind.CallOpts{Context: ctx, BlockNumber: confirmed}, fid)
+		if err != nil {
+			return nil, fmt.Errorf("reading holdings: %w", err)
+		}
+		deposits[i].Status = DepositPending
+		if final.Cmp(need) >= 0 {
+			deposits[i].Status = DepositFinal
+		}
+
+		it, err := filterer.FilterDeposited(&bind.FilterOpts{Context: ctx}, [][32]byte{fid})
+		if err != nil {
+			return nil, fmt.Errorf("filtering deposits: %w", err)
+		}
+		for it.Next() {
+			deposits[i].TxID = it.Event.Raw.TxHash.Hex()
+		}
+		it.Close()
+	}
+	return deposits, nil
+}
+
+// solDepositChecker checks deposits in the Perun program on Solana.
+type solDepositChecker struct {
+	rpcClient  *rpc.Client
+	perunAddr  solana.PublicKey
+	commitment rpc.CommitmentType
+}
+
+func (s *solDepositChecker) status(ctx context.Context, _ *channel.Params, state *channel.State, assetIdx int) (AssetFundingStatus, error) {
+	st := AssetFundingStatus{
+		Asset:    state.Assets[assetIdx],
+		Backend:  6,
+		Required: state.Allocation.Sum()[assetIdx],
+	}
+
+	// The program tracks the funding of both parties for all its assets.
+	needA := state.Balances[assetIdx][0].Sign() > 0
+	needB := len(state.Balances[assetIdx]) > 1 && state.Balances[assetIdx][1].Sign() > 0
+	funded := func(commitment rpc.CommitmentType) (bool, error) {
+		ctrl, ok, err := s.control(ctx, state.ID, commitment)
+		if err != nil || !ok {
+			return false, err
+		}
+		return (!needA || ctrl.FundedA) && (!needB || ctrl.FundedB), nil
+	}
+
+	final, err := funded(s.commitment)
+	if err != nil {
+		return st, err
+	}
+	if final {
+		st.Status = DepositFinal
+		st.Deposited = st.Required
+		return st, nil
+	}
+	processed, err := funded(rpc.CommitmentProcessed)
+	if err != nil {
+		return st, err
+	}
+	if processed {
+		st.Status = DepositPending
+	}
+	return st, nil
+}
+
+func (s *solDepositChecker) deposits(ctx context.Context, _ *channel.Params, state *channel.State, assetIdx int) ([]partDeposit, error) {
+	deposits := make([]partDeposit, len(state.Balances[assetIdx]))
+	final, _, err := s.control(ctx, state.ID, s.commitment)
+	if err != nil {
+		return nil, err
+	}
+	processed, _, err := s.control(ctx, state.ID, rpc.CommitmentProcessed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The program only tracks the funding of two parties.
+	funded := func(ctrl encoding.Control, i int) bool {
+		return (i == 0 && ctrl.FundedA) || (i == 1 && ctrl.FundedB)
+	}
+	for i := range deposits {
+		switch {
+		case state.Balances[assetIdx][i].Sign() == 0:
+			deposits[i].Status = DepositFinal
+			continue
+		case funded(final, i):
+			deposits[i].Status = DepositFinal
+		case funded(processed, i):
+			deposits[i].Status = DepositPending
+		default:
+			continue
+		}
+		deposits[i].TxID = s.latestSignature(ctx, state.ID)
+	}
+	return deposits, nil
+}
+
+// latestSignature returns the signature of the latest transaction on the
+// channel account, which is the deposit when it is first observed.
+func (s *solDepositChecker) latestSignature(ctx context.Context, id channel.ID) string {
+	pda, err := solclient.ChannelPDA(id, s.perunAddr)
+	if err != nil {
+		return ""
+	}
+	limit := 1
+	sigs, err := s.rpcClient.GetSignaturesForAddressWithOpts(ctx, pda, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil || len(sigs) == 0 {
+		return ""
+	}
+	return sigs[0].Signature.String()
+}
+
+// control reads the control flags of the channel account at the given
+// commitment. It returns false if the account does not exist yet.
+func (s *solDepositChecker) control(ctx context.Context, id channel.ID, commitment rpc.CommitmentType) (encoding.Control, bool, error) {
+	pda, err := solclient.ChannelPDA(id, s.perunAddr)
+	if err != nil {
+		return encoding.Control{}, false, fmt.Errorf("deriving channel account: %w", err)
+	}
+	info, err := s.rpcClient.GetAccountInfoWithOpts(ctx, pda, &rpc.GetAccountInfoOpts{Commitment: commitment})
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && (info == nil || info.Value == nil)) {
+		return encoding.Control{}, false, nil
+	}
+	if err != nil {
+		return encoding.Control{}, false, fmt.Errorf("reading channel account: %w", err)
+	}
+
+	var ch encoding.Channel
+	if err := bin.NewBorshDecoder(info.Value.Data.GetBinary()).Decode(&ch); err != nil {
+		return encoding.Control{}, false, fmt.Errorf("decoding channel account: %w", err)
+	}
+	return ch.Control, true, nil
+}
+
+// finalityFunder wraps a ledger funder and only reports success once the
+// deposits of its asset are final. If a deposit that was already observed
+// disappears again, funding fails with ErrDepositReorged. It does not wait for
+// the deposit to be included again, nor redeposit; the channel is then
+// recovered like any other failed funding.
+type finalityFunder struct {
+	channel.Funder
+	checker  depositChecker
+	backend  wallet.BackendID
+	interval time.Duration
+}
+
+// Fund implements channel.Funder.
+func (f *finalityFunder) Fund(ctx context.Context, req channel.FundingReq) error {
+	if err := f.Funder.Fund(ctx, req); err != nil {
+		return err
+	}
+
+	assetIdx := -1
+	for i, asset := range req.State.Assets {
+		if backendOf(asset) == f.backend {
+			assetIdx = i // All assets of a backend share the same deposits.
+			break
+		}
+	}
+	if assetIdx < 0 {
+		return nil
+	}
+
+	seen := false
+	for {
+		st, err := f.checker.status(ctx, req.Params, req.State, assetIdx)
+		switch {
+		case err != nil:
+			log.Printf("Checking deposit finality failed: %v", err)
+		case st.Status == DepositFinal:
+			return nil
+		case st.Status == DepositPending:
+			seen = true
+		case seen:
+			return fmt.Errorf("asset %d of channel %x: %w", assetIdx, req.Params.ID(), ErrDepositReorged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for deposit finality: %w", ctx.Err())
+		case <-time.After(f.interval):
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// FundingStage is the progress of a participant's deposit of an asset.
+type FundingStage int
+
+// Funding stages.
+const (
+	FundingAwaiting  FundingStage = iota // No deposit seen yet.
+	FundingSubmitted                     // Our deposit is being sent.
+	FundingObserved                      // The deposit is visible on-chain.
+	FundingConfirmed                     // The deposit is final.
+	FundingFailed                        // The deposit failed or timed out.
+)
+
+// String returns the name of the funding stage.
+func (s FundingStage) String() string {
+	switch s {
+	case FundingAwaiting:
+		return "awaiting"
+	case FundingSubmitted:
+		return "submitted"
+	case FundingObserved:
+		return "observed"
+	case FundingConfirmed:
+		return "confirmed"
+	case FundingFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// FundingProgress is the funding progress of a participant for an asset.
+type FundingProgress struct {
+	ChannelID   channel.ID
+	Asset       int
+	Backend     wallet.BackendID
+	Participant channel.Index
+	Stage       FundingStage
+	TxID        string // Ethereum transaction hash or Solana signature, if known.
+	Err         error  // Why funding failed.
+	Updated     time.Time
+}
+
+// FundingTimeouts bounds the funding on each chain, including waiting for the
+// peers' deposits. Zero means no bound besides the proposal's context.
+type FundingTimeouts struct {
+	Ethereum time.Duration
+	Solana   time.Duration
+}
+
+func (t FundingTimeouts) of(backend wallet.BackendID) time.Duration {
+	if backend == 1 {
+		return t.Ethereum
+	}
+	return t.Solana
+}
+
+// FundingCause is the cause of a failed funding.
+type FundingCause int
+
+// Funding causes.
+const (
+	FundingDepositFailed FundingCause = iota // Our deposit failed.
+	FundingPeerTimeout                       // Peers did not deposit in time.
+	FundingNotFinal                          // All deposits were seen, but did not become final in time.
+	FundingReorged                           // A deposit was dropped by a chain reorganization.
+)
+
+// FundingError is returned when funding a channel on one chain fails. It
+// tells whether peers did not deposit in time, the deposits did not become
+// final, or our own deposit failed.
+type FundingError struct {
+	Backend wallet.BackendID
+	Cause   FundingCause
+	Peers   []channel.Index // Participants that did not fund in ascending order, for FundingPeerTimeout.
+	Err     error
+}
+
+// Error implements error.
+func (e *FundingError) Error() string {
+	switch e.Cause {
+	case FundingPeerTimeout:
+		peers := make([]string, len(e.Peers))
+		for i, p := range e.Peers {
+			peers[i] = fmt.Sprint(p)
+		}
+		return fmt.Sprintf("peer %s never funded %s side: %v", strings.Join(peers, ", "), chainName(e.Backend), e.Err)
+	case FundingNotFinal:
+		return fmt.Sprintf("%s deposits did not become final in time: %v", chainName(e.Backend), e.Err)
+	case FundingReorged:
+		return fmt.Sprintf("%s deposit dropped by reorganization: %v", chainName(e.Backend), e.Err)
+	default:
+		return fmt.Sprintf("our %s deposit failed: %v", chainName(e.Backend), e.Err)
+	}
+}
+
+// Unwrap returns the underlying error.
+func (e *FundingError) Unwrap() error {
+	return e.Err
+}
+
+func chainName(backend wallet.BackendID) string {
+	if backend == 1 {
+		return "Ethereum"
+	}
+	return "Solana"
+}
+
+// FundingProgress returns the funding progress of all participants and
+// assets of the channel with the given ID.
+func (c *PaymentClient) FundingProgress(id channel.ID) []FundingProgress {
+	return c.funding.progress(id)
+}
+
+// fundingTracker keeps the funding progress of all channels being funded.
+type fundingTracker struct {
+	events *eventHub
+
+	mu       sync.Mutex
+	channels map[channel.ID][]FundingProgress
+}
+
+func newFundingTracker(events *eventHub) *fundingTracker {
+	return &fundingTracker{events: events, channels: make(map[channel.ID][]FundingProgress)}
+}
+
+func (t *fundingTracker) progress(id channel.ID) []FundingProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]FundingProgress(nil), t.channels[id]...)
+}
+
+// update sets the progress of a participant's deposit. Stages only advance,
+// except to FundingFailed.
+func (t *fundingTracker) update(p FundingProgress) {
+	t.mu.Lock()
+	entries := t.channels[p.ChannelID]
+	i := 0
+	for ; i < len(entries); i++ {
+		if entries[i].Asset == p.Asset && entries[i].Participant == p.Participant {
+			break
+		}
+	}
+	if i == len(entries) {
+		entries = append(entries, FundingProgress{})
+	} else if cur := entries[i]; cur.Stage == FundingFailed || (p.Stage <= cur.Stage && p.Stage != FundingFailed) {
+		t.mu.Unlock()
+		return
+	} else if p.TxID == "" {
+		p.TxID = cur.TxID
+	}
+	p.Updated = time.Now()
+	entries[i] = p
+	t.channels[p.ChannelID] = entries
+	t.mu.Unlock()
+
+	t.events.publish(Event{Type: FundingProgressed, ChannelID: p.ChannelID, Backend: p.Backend, Funding: &p})
+}
+
+// trackingFunder wraps a ledger funder, bounds its duration and tracks the
+// deposits of all participants on its chain while it runs.
+type trackingFunder struct {
+	channel.Funder
+	backend  wallet.BackendID
+	checker  depositChecker
+	tracker  *fundingTracker
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// Fund implements channel.Funder.
+func (f *trackingFunder) Fund(ctx context.Context, req channel.FundingReq) error {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	var assets []int
+	for i, asset := range req.State.Assets {
+		if backendOf(asset) == f.backend {
+			assets = append(assets, i)
+		}
+	}
+	for _, a := range assets {
+		for p := range req.Params.Parts {
+			stage := FundingAwaiting
+			if channel.Index(p) == req.Idx && req.Agreement[a][p].Sign() > 0 {
+				stage = FundingSubmitted
+			}
+			f.report(req, a, channel.Index(p), stage, "", nil)
+		}
+	}
+
+	done := make(chan struct{})
+	go f.poll(ctx, req, assets, done)
+	err := f.Funder.Fund(ctx, req)
+	close(done)
+
+	if err != nil {
+		ferr := f.classify(ctx, req, err)
+		failed := map[channel.Index]bool{req.Idx: true}
+		switch ferr.Cause {
+		case FundingPeerTimeout:
+			failed = make(map[channel.Index]bool)
+			for _, p := range ferr.Peers {
+				failed[p] = true
+			}
+		case FundingNotFinal, FundingReorged:
+			for p := range req.Params.Parts {
+				failed[channel.Index(p)] = true
+			}
+		}
+		for _, a := range assets {
+			for p := range failed {
+				f.report(req, a, p, FundingFailed, "", ferr)
+			}
+		}
+		return ferr
+	}
+	for _, a := range assets {
+		for p := range req.Params.Parts {
+			f.report(req, a, channel.Index(p), FundingConfirmed, "", nil)
+		}
+	}
+	return nil
+}
+
+// poll reports the observed deposits until funding is done.
+func (f *trackingFunder) poll(ctx context.Context, req channel.FundingReq, assets []int, done <-chan struct{}) {
+	for {
+		for _, a := range assets {
+			deposits, err := f.checker.deposits(ctx, req.Params, req.State, a)
+			if err != nil {
+				continue
+			}
+			for p, d := range deposits {
+				switch d.Status {
+				case DepositPending:
+					f.report(req, a, channel.Index(p), FundingObserved, d.TxID, nil)
+				case DepositFinal:
+					f.report(req, a, channel.Index(p), FundingConfirmed, d.TxID, nil)
+				}
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(f.interval):
+		}
+	}
+}
+
+// missing returns the participants whose deposit on our chain was not observed,
+// other than us.
+func (f *trackingFunder) missing(req channel.FundingReq) []channel.Index {
+	missing := make(map[channel.Index]bool)
+	for _, p := range f.tracker.progress(req.Params.ID()) {
+		if p.Backend == f.backend && p.Participant != req.Idx && p.Stage < FundingObserved && req.Agreement[p.Asset][p.Participant].Sign() > 0 {
+			missing[p.Participant] = true
+		}
+	}
+	return sortedIndices(missing)
+}
+
+// classify determines the cause of a failed funding on our chain. Peers
+// count as not having funded only if the funder reports them and we did not
+// observe their deposit. Funders may list ourselves as timed out, which means
+// that our own deposit failed.
+func (f *trackingFunder) classify(ctx context.Context, req channel.FundingReq, err error) *FundingError {
+	ferr := &FundingError{Backend: f.backend, Cause: FundingDepositFailed, Err: err}
+	if errors.Is(err, ErrDepositReorged) {
+		ferr.Cause = FundingReorged
+		return ferr
+	}
+
+	var timeout channel.FundingTimeoutError
+	timedOut := errors.As(err, &timeout)
+	if !timedOut && ctx.Err() == nil {
+		return ferr
+	}
+	missing := f.missing(req)
+	if timedOut {
+		listed := make(map[channel.Index]bool)
+		for _, e := range timeout.Errors {
+			for _, p := range e.TimedOutPeers {
+				listed[p] = true
+			}
+		}
+		if listed[req.Idx] && len(listed) == 1 {
+			return ferr
+		}
+		peers := make([]channel.Index, 0, len(missing))
+		for _, p := range missing {
+			if listed[p] {
+				peers = append(peers, p)
+			}
+		}
+		missing = peers
+	}
+	switch {
+	case !f.depositObserved(req, req.Idx):
+		// Our deposit never reached the chain.
+	case len(missing) > 0:
+		ferr.Cause, ferr.Peers = FundingPeerTimeout, missing
+	default:
+		// All deposits were seen, but did not become final before the
+		// deadline.
+		ferr.Cause = FundingNotFinal
+	}
+	return ferr
+}
+
+// depositObserved returns whether the participant's deposits on our chain
+// were seen on-chain, or it does not deposit anything on it.
+func (f *trackingFunder) depositObserved(req channel.FundingReq, part channel.Index) bool {
+	for _, p := range f.tracker.progress(req.Params.ID()) {
+		if p.Backend == f.backend && p.Participant == part && req.Agreement[p.Asset][part].Sign() > 0 &&
+			(p.Stage < FundingObserved || p.Stage == FundingFailed) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedIndices returns the set indices in ascending order.
+func sortedIndices(set map[channel.Index]bool) []channel.Index {
+	indices := make([]channel.Index, 0, len(set))
+	for i := range set {
+		indices = append(indices, i)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	return indices
+}
+
+func (f *trackingFunder) report(req channel.FundingReq, asset int, part channel.Index, stage FundingStage, txID string, err error) {
+	f.tracker.update(FundingProgress{
+		ChannelID:   req.Params.ID(),
+		Asset:       asset,
+		Backend:     f.backend,
+		Participant: part,
+		Stage:       stage,
+		TxID:        txID,
+		Err:         err,
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"perun.network/go-perun/channel"
+)
+
+func TestClassifyFundingError(t *testing.T) {
+	timeoutOf := func(peers ...channel.Index) error {
+		return channel.FundingTimeoutError{Errors: []*channel.AssetFundingError{{Asset: 0, TimedOutPeers: peers}}}
+	}
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		observed []channel.Index // Participants whose deposit was seen.
+		cause    FundingCause
+		peers    []channel.Index
+	}{
+		{"deposit error", context.Background(), errors.New("tx failed"), []channel.Index{1}, FundingDepositFailed, nil},
+		{"reorg", context.Background(), fmt.Errorf("asset 0: %w", ErrDepositReorged), []channel.Index{0, 1}, FundingReorged, nil},
+		{"peer timed out", context.Background(), timeoutOf(1), []channel.Index{0}, FundingPeerTimeout, []channel.Index{1}},
+		{"only we timed out", context.Background(), timeoutOf(0), []channel.Index{1}, FundingDepositFailed, nil},
+		{"we are listed with the peer", context.Background(), timeoutOf(0, 1), []channel.Index{0}, FundingPeerTimeout, []channel.Index{1}},
+		{"listed peer was observed", context.Background(), timeoutOf(1), []channel.Index{0, 1}, FundingNotFinal, nil},
+		{"timeout without our deposit", context.Background(), timeoutOf(1), nil, FundingDepositFailed, nil},
+		{"deadline, peer missing", expired, context.Canceled, []channel.Index{0}, FundingPeerTimeout, []channel.Index{1}},
+		{"deadline, not final", expired, context.Canceled, []channel.Index{0, 1}, FundingNotFinal, nil},
+		{"deadline without our deposit", expired, context.Canceled, []channel.Index{1}, FundingDepositFailed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &trackingFunder{backend: 6, tracker: newFundingTracker(newEventHub())}
+			req := channel.FundingReq{
+				Params:    &channel.Params{},
+				Idx:       0,
+				Agreement: channel.Balances{{big.NewInt(1), big.NewInt(2)}},
+			}
+			for p := range req.Agreement[0] {
+				f.report(req, 0, channel.Index(p), FundingAwaiting, "", nil)
+			}
+			for _, p := range tt.observed {
+				f.report(req, 0, p, FundingObserved, "", nil)
+			}
+
+			ferr := f.classify(tt.ctx, req, tt.err)
+			if ferr.Cause != tt.cause {
+				t.Errorf("cause = %d, want %d", ferr.Cause, tt.cause)
+			}
+			if len(ferr.Peers) != 0 || len(tt.peers) != 0 {
+				if !reflect.DeepEqual(ferr.Peers, tt.peers) {
+					t.Errorf("peers = %v, want %v", ferr.Peers, tt.peers)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,470 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/perun-network/perun-eth-backend/bindings/adjudicator"
+	"github.com/perun-network/perun-eth-backend/bindings/assetholdereth"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+)
+
+// GasOperation is the kind of an Ethereum transaction sent by the client.
+type GasOperation string
+
+// Operations with configurable gas limits.
+const (
+	GasDeposit  GasOperation = "deposit"
+	GasRegister GasOperation = "register"
+	GasProgress GasOperation = "progress"
+	GasConclude GasOperation = "conclude"
+	GasWithdraw GasOperation = "withdraw"
+)
+
+const (
+	defaultBumpPercent = 15 // Nodes require at least 10% higher fees for replacements.
+	feeHistoryBlocks   = 20 // Number of blocks considered by the fee oracle.
+
+	// replacementRetention is how long the replacements of a transaction are
+	// remembered after monitoring it ended, for callers still waiting for
+	// its receipt.
+	replacementRetention = 10 * time.Minute
+)
+
+// errFeeCapReached is returned when a transaction cannot be bumped because its
+// fees are already at the strategy's cap.
+var errFeeCapReached = errors.New("fee cap reached")
+
+// GasFees are the fee parameters of a transaction. If GasPrice is set, a
+// legacy transaction is sent. Otherwise, an EIP-1559 transaction with the
+// given fee cap and tip is sent.
+type GasFees struct {
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// GasStrategy determines the fees of the next transaction.
+type GasStrategy interface {
+	Fees(ctx context.Context) (GasFees, error)
+}
+
+// GasConfig configures the fees, gas limits and resubmission of the Ethereum
+// transactions of a client.
+type GasConfig struct {
+	Strategy    GasStrategy             // Fee strategy, node defaults if nil.
+	Limits      map[GasOperation]uint64 // Gas limit per operation.
+	BumpAfter   time.Duration           // Resubmit transactions not mined after this duration, 0 disables.
+	BumpPercent int64                   // Fee increase per resubmission, up to the MaxFeeCap of a FeeOracle.
+	MaxBumps    int                     // Maximum number of resubmissions.
+}
+
+// DefaultGasConfig returns the gas configuration used by default. It uses the
+// fees suggested by the node and does not resubmit transactions.
+func DefaultGasConfig() GasConfig {
+	return GasConfig{
+		Limits: map[GasOperation]uint64{
+			GasDeposit:  50000,
+			GasRegister: 1000000,
+			GasProgress: 1000000,
+			GasConclude: 1000000,
+			GasWithdraw: 1000000,
+		},
+		BumpPercent: defaultBumpPercent,
+	}
+}
+
+// limit returns the gas limit of the given operation or the fallback.
+func (cfg GasConfig) limit(op GasOperation, fallback uint64) uint64 {
+	if l, ok := cfg.Limits[op]; ok && l > 0 {
+		return l
+	}
+	return fallback
+}
+
+// FixedGasPrice is a strategy that sends legacy transactions with a fixed gas
+// price.
+type FixedGasPrice struct {
+	GasPrice *big.Int
+}
+
+// Fees implements GasStrategy.
+func (s FixedGasPrice) Fees(context.Context) (GasFees, error) {
+	return GasFees{GasPrice: s.GasPrice}, nil
+}
+
+// FixedFees is a strategy that sends EIP-1559 transactions with a fixed fee
+// cap and priority tip.
+type FixedFees struct {
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// Fees implements GasStrategy.
+func (s FixedFees) Fees(context.Context) (GasFees, error) {
+	return GasFees{GasFeeCap: s.GasFeeCap, GasTipCap: s.GasTipCap}, nil
+}
+
+// FeeOracle is a strategy that derives EIP-1559 fees from eth_feeHistory. The
+// tip is the median of the given reward percentile over the recent blocks,
+// raised to MinTip. The fee cap is twice the next base fee plus the tip,
+// capped at MaxFeeCap.
+type FeeOracle struct {
+	Reader     ethereum.FeeHistoryReader
+	Percentile float64  // Reward percentile, e.g. 50.
+	MinTip     *big.Int // Lower bound of the tip, optional.
+	MaxFeeCap  *big.Int // Upper bound of the fee cap, optional.
+}
+
+// Fees implements GasStrategy.
+func (s FeeOracle) Fees(ctx context.Context) (GasFees, error) {
+	h, err := s.Reader.FeeHistory(ctx, feeHistoryBlocks, nil, []float64{s.Percentile})
+	if err != nil {
+		return GasFees{}, fmt.Errorf("fetching fee history: %w", err)
+	}
+	if len(h.BaseFee) == 0 {
+		return GasFees{}, fmt.Errorf("empty fee history")
+	}
+
+	// The last base fee is the one of the next block.
+	baseFee := h.BaseFee[len(h.BaseFee)-1]
+	tips := make([]*big.Int, 0, len(h.Reward))
+	for _, r := range h.Reward {
+		if len(r) > 0 && r[0] != nil {
+			tips = append(tips, r[0])
+		}
+	}
+	tip := big.NewInt(0)
+	if len(tips) > 0 {
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		tip = new(big.Int).Set(tips[len(tips)/2])
+	}
+	if s.MinTip != nil && tip.Cmp(s.MinTip) < 0 {
+		tip = new(big.Int).Set(s.MinTip)
+	}
+
+	feeCap := new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip)
+	if s.MaxFeeCap != nil && feeCap.Cmp(s.MaxFeeCap) > 0 {
+		feeCap = new(big.Int).Set(s.MaxFeeCap)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+	return GasFees{GasFeeCap: feeCap, GasTipCap: tip}, nil
+}
+
+// maxFee returns the cap of the fee cap, or nil if unbounded.
+func (s FeeOracle) maxFee() *big.Int {
+	return s.MaxFeeCap
+}
+
+// feeCapper is implemented by strategies whose fees are bounded. Resubmitted
+// transactions are not bumped beyond the bound.
+type feeCapper interface {
+	maxFee() *big.Int
+}
+
+// gasOperations maps the method IDs of the Perun contracts to operations.
+var gasOperations = func() map[[4]byte]GasOperation {
+	ops := make(map[[4]byte]GasOperation)
+	add := func(md *bind.MetaData, names map[string]GasOperation) {
+		parsed, err := md.GetAbi()
+		if err != nil {
+			panic(err)
+		}
+		for name, op := range names {
+			var id [4]byte
+			copy(id[:], parsed.Methods[name].ID)
+			ops[id] = op
+		}
+	}
+	add(adjudicator.AdjudicatorMetaData, map[string]GasOperation{
+		"register":      GasRegister,
+		"progress":      GasProgress,
+		"conclude":      GasConclude,
+		"concludeFinal": GasConclude,
+	})
+	add(assetholdereth.AssetholderethMetaData, map[string]GasOperation{
+		"deposit":  GasDeposit,
+		"withdraw": GasWithdraw,
+	})
+	return ops
+}()
+
+// operationOf returns the operation of a transaction calling a Perun contract.
+func operationOf(data []byte) (GasOperation, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+	var id [4]byte
+	copy(id[:], data[:4])
+	op, ok := gasOperations[id]
+	return op, ok
+}
+
+// gasTransactor sets the gas limit and fees of each transaction according to
+// the gas configuration before it is signed.
+type gasTransactor struct {
+	tr      ethchannel.Transactor
+	cfg     GasConfig
+	chainID *big.Int
+}
+
+// NewTransactor implements ethchannel.Transactor.
+func (t *gasTransactor) NewTransactor(acc accounts.Account) (*bind.TransactOpts, error) {
+	opts, err := t.tr.NewTransactor(acc)
+	if err != nil {
+		return nil, err
+	}
+
+	sign := opts.Signer
+	opts.Signer = func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		ctx := opts.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		tx, err := t.adjust(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		return sign(addr, tx)
+	}
+	return opts, nil
+}
+
+// adjust returns a copy of the unsigned transaction with the configured gas
+// limit and fees.
+func (t *gasTransactor) adjust(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	gas := tx.Gas()
+	if op, ok := operationOf(tx.Data()); ok {
+		gas = t.cfg.limit(op, gas)
+	}
+
+	fees := GasFees{GasPrice: tx.GasPrice(), GasFeeCap: tx.GasFeeCap(), GasTipCap: tx.GasTipCap()}
+	if tx.Type() != types.LegacyTxType {
+		fees.GasPrice = nil
+	}
+	if t.cfg.Strategy != nil {
+		var err error
+		fees, err = t.cfg.Strategy.Fees(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("determining gas fees: %w", err)
+		}
+	}
+	return newTxWithFees(tx, t.chainID, gas, fees), nil
+}
+
+// newTxWithFees creates an unsigned copy of tx with the given gas and fees.
+func newTxWithFees(tx *types.Transaction, chainID *big.Int, gas uint64, fees GasFees) *types.Transaction {
+	if fees.GasPrice != nil {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: fees.GasPrice,
+			Gas:      gas,
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		})
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     tx.Nonce(),
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+		Gas:       gas,
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	})
+}
+
+// bumpingBackend resubmits transactions that are not mined in time with
+// higher fees. Receipts are looked up for the original transaction and all its
+// replacements, so callers can keep waiting for the original hash.
+type bumpingBackend struct {
+	ethchannel.ContractInterface
+	ctx    context.Context // Ends monitoring when done.
+	tr     ethchannel.Transactor
+	signer types.Signer
+	cfg    GasConfig
+
+	mu           sync.Mutex
+	replacements map[common.Hash][]common.Hash // Original hash to replacement hashes.
+}
+
+func newBumpingBackend(ctx context.Context, ci ethchannel.ContractInterface, tr ethchannel.Transactor, signer types.Signer, cfg GasConfig) *bumpingBackend {
+	return &bumpingBackend{
+		ContractInterface: ci,
+		ctx:               ctx,
+		tr:                tr,
+		signer:            signer,
+		cfg:               cfg,
+		replacements:      make(map[common.Hash][]common.Hash),
+	}
+}
+
+// SendTransaction sends the transaction and starts monitoring it.
+func (b *bumpingBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := b.ContractInterface.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	go b.monitor(tx)
+	return nil
+}
+
+// TransactionReceipt returns the receipt of the transaction or of one of its
+// replacements.
+func (b *bumpingBackend) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	b.mu.Lock()
+	hashes := append([]common.Hash{hash}, b.replacements[hash]...)
+	b.mu.Unlock()
+
+	var lastErr error
+	for i := len(hashes) - 1; i >= 0; i-- {
+		r, err := b.ContractInterface.TransactionReceipt(ctx, hashes[i])
+		if err == nil && r != nil {
+			return r, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// monitor resubmits the transaction with bumped fees until one of its
+// versions is mined, the maximum number of bumps or the fee cap is reached, or
+// the backend's context is done. The replacements are forgotten
+// replacementRetention after monitoring ended.
+func (b *bumpingBackend) monitor(orig *types.Transaction) {
+	defer b.prune(orig.Hash())
+	from, err := types.Sender(b.signer, orig)
+	if err != nil {
+		log.Printf("Gas: cannot monitor transaction %v: %v", orig.Hash(), err)
+		return
+	}
+
+	current := orig
+	for bumps := 0; bumps < b.cfg.MaxBumps; bumps++ {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(b.cfg.BumpAfter):
+		}
+
+		ctx, cancel := context.WithTimeout(b.ctx, b.cfg.BumpAfter)
+		if r, err := b.TransactionReceipt(ctx, orig.Hash()); err == nil && r != nil {
+			cancel()
+			return
+		}
+
+		next, err := b.bump(current, from)
+		if errors.Is(err, errFeeCapReached) {
+			cancel()
+			log.Printf("Gas: not resubmitting transaction %v: %v", orig.Hash(), err)
+			return
+		}
+		if err == nil {
+			err = b.ContractInterface.SendTransaction(ctx, next)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("Gas: resubmitting transaction %v failed: %v", orig.Hash(), err)
+			continue
+		}
+
+		log.Printf("Gas: resubmitted transaction %v as %v", orig.Hash(), next.Hash())
+		b.mu.Lock()
+		b.replacements[orig.Hash()] = append(b.replacements[orig.Hash()], next.Hash())
+		b.mu.Unlock()
+		current = next
+	}
+}
+
+// prune forgets the replacements of the transaction after
+// replacementRetention, or immediately once the backend's context is done.
+func (b *bumpingBackend) prune(hash common.Hash) {
+	select {
+	case <-b.ctx.Done():
+	case <-time.After(replacementRetention):
+	}
+	b.mu.Lock()
+	delete(b.replacements, hash)
+	b.mu.Unlock()
+}
+
+// bump returns a signed replacement of tx with increased fees, clamped to the
+// strategy's cap. It returns errFeeCapReached if the fees cannot be increased.
+func (b *bumpingBackend) bump(tx *types.Transaction, from common.Address) (*types.Transaction, error) {
+	fees, err := bumpedFees(tx, b.cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := b.tr.NewTransactor(accounts.Account{Address: from})
+	if err != nil {
+		return nil, err
+	}
+	return opts.Signer(from, newTxWithFees(tx, tx.ChainId(), tx.Gas(), fees))
+}
+
+// bumpedFees returns the fees of tx increased by the configured percentage.
+// The fee cap or gas price is clamped to the cap of the strategy, and the tip
+// to the fee cap.
+func bumpedFees(tx *types.Transaction, cfg GasConfig) (GasFees, error) {
+	pct := cfg.BumpPercent
+	if pct <= 0 {
+		pct = defaultBumpPercent
+	}
+	var limit *big.Int
+	if c, ok := cfg.Strategy.(feeCapper); ok {
+		limit = c.maxFee()
+	}
+	inc := func(v *big.Int) *big.Int {
+		r := new(big.Int).Mul(v, big.NewInt(100+pct))
+		r.Div(r, big.NewInt(100))
+		if limit != nil && r.Cmp(limit) > 0 {
+			r.Set(limit)
+		}
+		return r
+	}
+
+	if tx.Type() == types.LegacyTxType {
+		price := inc(tx.GasPrice())
+		if price.Cmp(tx.GasPrice()) <= 0 {
+			return GasFees{}, errFeeCapReached
+		}
+		return GasFees{GasPrice: price}, nil
+	}
+	feeCap := inc(tx.GasFeeCap())
+	if feeCap.Cmp(tx.GasFeeCap()) <= 0 {
+		return GasFees{}, errFeeCapReached
+	}
+	tip := inc(tx.GasTipCap())
+	if tip.Cmp(feeCap) > 0 {
+		tip.Set(feeCap)
+	}
+	return GasFees{GasFeeCap: feeCap, GasTipCap: tip}, nil
+}
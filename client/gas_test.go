@@ -0,0 +1,78 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestBumpedFees(t *testing.T) {
+	legacy := func(price int64) *types.Transaction {
+		return types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(price)})
+	}
+	dynamic := func(feeCap, tip int64) *types.Transaction {
+		return types.NewTx(&types.DynamicFeeTx{GasFeeCap: big.NewInt(feeCap), GasTipCap: big.NewInt(tip)})
+	}
+	capped := func(limit int64) GasConfig {
+		return GasConfig{BumpPercent: 20, Strategy: FeeOracle{MaxFeeCap: big.NewInt(limit)}}
+	}
+
+	tests := []struct {
+		name   string
+		tx     *types.Transaction
+		cfg    GasConfig
+		want   GasFees
+		capped bool
+	}{
+		{"legacy", legacy(100), GasConfig{BumpPercent: 20}, GasFees{GasPrice: big.NewInt(120)}, false},
+		{"dynamic", dynamic(100, 10), GasConfig{BumpPercent: 20}, GasFees{GasFeeCap: big.NewInt(120), GasTipCap: big.NewInt(12)}, false},
+		{"default percent", legacy(100), GasConfig{}, GasFees{GasPrice: big.NewInt(115)}, false},
+		{"clamped fee cap", dynamic(100, 10), capped(110), GasFees{GasFeeCap: big.NewInt(110), GasTipCap: big.NewInt(12)}, false},
+		{"clamped tip", dynamic(100, 100), capped(110), GasFees{GasFeeCap: big.NewInt(110), GasTipCap: big.NewInt(110)}, false},
+		{"clamped gas price", legacy(100), capped(105), GasFees{GasPrice: big.NewInt(105)}, false},
+		{"fee cap reached", dynamic(110, 10), capped(110), GasFees{}, true},
+		{"gas price reached", legacy(110), capped(100), GasFees{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bumpedFees(tt.tx, tt.cfg)
+			if tt.capped {
+				if !errors.Is(err, errFeeCapReached) {
+					t.Fatalf("got %v, %v, want errFeeCapReached", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range []struct {
+				name      string
+				got, want *big.Int
+			}{
+				{"gas price", got.GasPrice, tt.want.GasPrice},
+				{"fee cap", got.GasFeeCap, tt.want.GasFeeCap},
+				{"tip", got.GasTipCap, tt.want.GasTipCap},
+			} {
+				if (f.got == nil) != (f.want == nil) || (f.got != nil && f.got.Cmp(f.want) != 0) {
+					t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
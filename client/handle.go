@@ -16,88 +16,156 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"math/big"
 	"time"
 
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
 )
 
 // HandleProposal is the callback for incoming channel proposals.
 func (c *PaymentClient) HandleProposal(p client.ChannelProposal, r *client.ProposalResponder) {
 	log.Println("Received channel proposal")
-	lcp, err := func() (*client.LedgerChannelProposalMsg, error) {
-		// Ensure that we got a ledger channel proposal.
-		lcp, ok := p.(*client.LedgerChannelProposalMsg)
-		if !ok {
-			return nil, fmt.Errorf("invalid proposal type: %T", p)
+	base := p.Base()
+	c.events.publish(Event{
+		Type: ProposalReceived,
+		Proposal: &ProposalInfo{
+			ProposalID:        base.ProposalID,
+			ChallengeDuration: base.ChallengeDuration,
+			NumPeers:          base.NumPeers(),
+		},
+		New: base.InitBals,
+	})
+	err := func() error {
+		if err := checkProposal(base, c.currency); err != nil {
+			return err
 		}
 
-		// Check that we have the correct number of participants.
-		if lcp.NumPeers() != 2 {
-			return nil, fmt.Errorf("invalid number of participants: %d", lcp.NumPeers())
+		// Check that the channel has the expected funding balances. Our
+		// share of sub-channels and virtual channels is taken from existing
+		// channels, so it counts against the same limits.
+		const peerIdx = 1
+		lcp, ok := p.(*client.LedgerChannelProposalMsg)
+		if !ok {
+			return checkFundingLimits(base.InitBals.Assets, base.InitBals.Balances, peerIdx, c.fundingLimits)
 		}
 
-		// Check that the channel has the expected assets and funding balances.
-		const assetIdx, peerIdx = 0, 1
-		if err := channel.AssertAssetsEqual(lcp.InitBals.Assets, c.currency); err != nil {
-			return nil, fmt.Errorf("invalid assets: %v", err)
-		} else if lcp.FundingAgreement[assetIdx][peerIdx].Cmp(big.NewInt(0)) != 0 {
-			return nil, fmt.Errorf("invalid funding balance")
+		// The successor of a channel rolled over by the proposer may require
+		// us to deposit our carried-over balance.
+		if pred, ok := c.predecessor(lcp); ok {
+			if err := checkRollover(pred, lcp, peerIdx, c.fundingLimits); err != nil {
+				return fmt.Errorf("invalid rollover: %v", err)
+			}
+			return nil
 		}
-		return lcp, nil
+		return checkFundingLimits(lcp.InitBals.Assets, lcp.FundingAgreement, peerIdx, c.fundingLimits)
 	}()
 	if err != nil {
 		log.Println("Rejecting proposal: ", err)
 		r.Reject(context.TODO(), err.Error()) //nolint:errcheck // It's OK if rejection fails.
+		return
 	}
 
-	// Create a channel accept message and send it.
-	accept := lcp.Accept(
-		c.account,                // The account we use in the channel.
-		client.WithRandomNonce(), // Our share of the channel nonce.
-	)
-	// ctx, cancel := context.WithTimeout(context.Background(), 200*time.Second)
-	// defer cancel()
+	// Create a channel accept message with our share of the channel nonce.
+	var accept client.ChannelProposalAccept
+	switch p := p.(type) {
+	case *client.LedgerChannelProposalMsg:
+		accept = p.Accept(c.account, client.WithRandomNonce())
+	case *client.SubChannelProposalMsg:
+		accept = p.Accept(client.WithRandomNonce())
+	case *client.VirtualChannelProposalMsg:
+		accept = p.Accept(c.account, client.WithRandomNonce())
+	}
+
+	// Send the accept message.
 	log.Println("Accepting proposal: ", accept)
 	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Second)
 	defer cancel()
 	ch, err := r.Accept(ctx, accept)
-	if err != nil {
+	var fundingErr *client.ChannelFundingError
+	if errors.As(err, &fundingErr) && ch != nil {
+		fmt.Printf("Error funding channel: %v\n", c.recoverFunding(ch, err))
+		return
+	} else if err != nil {
 		fmt.Printf("Error accepting channel proposal: %v\n", err)
 		return
 	}
 
-	// Start the on-chain event watcher. It automatically handles disputes.
-	c.startWatching(ch)
+	// Withdraw our funds from the predecessor of a rolled over channel, which
+	// the proposer concludes once the successor is funded.
+	if lcp, ok := p.(*client.LedgerChannelProposalMsg); ok {
+		if pred, ok := c.predecessor(lcp); ok {
+			log.Println("Settling predecessor of rolled over channel", pred.ID())
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Second)
+			defer cancel()
+			if err := c.settle(ctx, pred, true); err != nil {
+				fmt.Printf("Error settling predecessor: %v\n", err)
+			}
+		}
+	}
 
 	// Store channel.
-	c.channels <- newPaymentChannel(ch, c.currency)
+	c.queueAccepted(c.openedChannel(ch))
 }
 
 // HandleUpdate is the callback for incoming channel updates.
 func (c *PaymentClient) HandleUpdate(cur *channel.State, next client.ChannelUpdate, r *client.UpdateResponder) {
-	// We accept every update that increases our balance.
+	// We accept every update that does not decrease the balance of any
+	// participant other than the actor. The transitions of app channels are
+	// validated by their app instead, and swaps by our orders or their price.
+	var fill *OrderFillMsg
+	var payment *streamPayment
+	var fwd *forward
 	err := func() error {
 		err := channel.AssertAssetsEqual(cur.Assets, next.State.Assets)
 		if err != nil {
 			return fmt.Errorf("invalid assets: %v", err)
 		}
+		if cur.NumParts() != next.State.NumParts() {
+			return fmt.Errorf("invalid number of participants: %d", next.State.NumParts())
+		}
+		ch, err := c.perunClient.Channel(next.State.ID)
+		if err != nil {
+			return fmt.Errorf("unknown channel: %v", err)
+		}
 
-		receiverIdx := 1 - next.ActorIdx // This works because we are in a two-party channel.
-		curBal := cur.Allocation.Balance(receiverIdx, c.currency[0])
-		nextBal := next.State.Allocation.Balance(receiverIdx, c.currency[0])
-		if nextBal.Cmp(curBal) < 0 {
-			return fmt.Errorf("invalid balance: %v", nextBal)
+		// A swap must fill one of our orders or be worth its price.
+		fill, err = c.checkOrderFill(cur, next.State, ch.Idx())
+		if err != nil {
+			return err
+		}
+		swap := fill != nil
+		if !swap {
+			if swap, err = checkSwap(c.oracle, c.slippage, cur, next); err != nil {
+				return err
+			}
 		}
-		return nil
+
+		if !swap && channel.IsNoApp(next.State.App) {
+			if err := checkNoDecrease(cur, next.State, next.ActorIdx); err != nil {
+				return err
+			}
+		}
+
+		// Payments of an expected stream must match its rate.
+		payment, err = c.checkStream(cur, next.State, ch.Idx(), time.Now())
+		if err != nil {
+			return err
+		}
+
+		// An update announced to be forwarded must lock a payment for us
+		// that we can forward.
+		fwd, err = c.checkForward(cur, next.State, ch.Idx())
+		return err
 	}()
 	if err != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Second)
 		defer cancel()
 		r.Reject(ctx, err.Error()) //nolint:errcheck // It's OK if rejection fails.
+		return
 	}
 
 	// Send the acceptance message.
@@ -105,11 +173,79 @@ func (c *PaymentClient) HandleUpdate(cur *channel.State, next client.ChannelUpda
 	defer cancel()
 	err = r.Accept(ctx)
 	if err != nil {
-		panic(err)
+		log.Printf("Error accepting update of channel %x: %v", next.State.ID, err)
+		if fwd != nil {
+			c.hub.take(fwd)
+		}
+		return
+	}
+	if fwd != nil {
+		go c.runForward(fwd)
+	}
+	c.forwardsResolved(cur, next.State)
+	if payment != nil {
+		c.streamPaid(payment)
+	}
+	if fill != nil {
+		c.orderFilled(fill.ID, fill.Amount)
+	}
+	if ch, err := c.perunClient.Channel(next.State.ID); err == nil {
+		c.paymentReceived(cur, next.State, ch.Idx())
+		c.notifyPayment(cur, next.State, ch.Idx())
 	}
 }
 
 // HandleAdjudicatorEvent is the callback for smart contract events.
 func (c *PaymentClient) HandleAdjudicatorEvent(e channel.AdjudicatorEvent) {
 	log.Printf("Adjudicator event: type = %T, client = %v", e, c.account)
+	c.events.publishAdjudicatorEvent(e)
+	c.notifyAdjudicatorEvent(e)
+}
+
+// checkProposal checks that a proposal has a supported number of
+// participants, a bounded challenge duration and only the given currencies.
+func checkProposal(base *client.BaseChannelProposal, currencies []channel.Asset) error {
+	if base.NumPeers() < 2 || base.NumPeers() > maxParticipants {
+		return fmt.Errorf("invalid number of participants: %d", base.NumPeers())
+	}
+	if base.ChallengeDuration > maxChallengeDuration {
+		return fmt.Errorf("challenge duration of %d seconds exceeds %d", base.ChallengeDuration, maxChallengeDuration)
+	}
+	for _, asset := range base.InitBals.Assets {
+		if !containsAsset(currencies, asset) {
+			return fmt.Errorf("invalid asset: %v", asset)
+		}
+	}
+	return nil
+}
+
+// checkFundingLimits checks that the participant with the given index does
+// not have to fund more than the limit of each chain.
+func checkFundingLimits(assets []channel.Asset, funding channel.Balances, idx channel.Index, limits map[wallet.BackendID]channel.Bal) error {
+	for a, asset := range assets {
+		limit, ok := limits[backendOf(asset)]
+		if ok && funding[a][idx].Cmp(limit) > 0 {
+			return fmt.Errorf("invalid funding balance of asset %d", a)
+		}
+	}
+	return nil
+}
+
+// checkNoDecrease checks that the update does not decrease the balance of any
+// participant other than the actor.
+func checkNoDecrease(cur, next *channel.State, actor channel.Index) error {
+	for idx := range cur.NumParts() {
+		receiverIdx := channel.Index(idx)
+		if receiverIdx == actor {
+			continue
+		}
+		for a := range cur.Assets {
+			curBal := cur.Balances[a][receiverIdx]
+			nextBal := next.Balances[a][receiverIdx]
+			if nextBal.Cmp(curBal) < 0 {
+				return fmt.Errorf("invalid balance of participant %d: %v", receiverIdx, nextBal)
+			}
+		}
+	}
+	return nil
 }
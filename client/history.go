@@ -0,0 +1,46 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"perun.network/go-perun/channel"
+	"perun.network/sol-eth-cross-chain-demo/history"
+)
+
+// History returns the recorded states of the channel with the given ID.
+func (c *PaymentClient) History(id channel.ID) (*history.History, error) {
+	if c.watcher.history == nil {
+		return nil, errors.New("history not enabled")
+	}
+	return c.watcher.history.Load(id)
+}
+
+// AuditBundle returns the histories of the given channels signed with the
+// operator's key.
+func (c *PaymentClient) AuditBundle(key *ecdsa.PrivateKey, ids ...channel.ID) (*history.Bundle, error) {
+	histories := make([]*history.History, 0, len(ids))
+	for _, id := range ids {
+		h, err := c.History(id)
+		if err != nil {
+			return nil, fmt.Errorf("loading history of channel %x: %w", id, err)
+		}
+		histories = append(histories, h)
+	}
+	return history.NewBundle(key, histories...)
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/sol-eth-cross-chain-demo/htlc"
+)
+
+// LockPayment locks an amount of the asset for the peer of an HTLC channel.
+// The peer receives it by revealing the preimage of the hashlock before the
+// timeout, or gives it back with ReleasePayment. After the timeout, we can
+// take it back with RefundPayment. The timeout is only enforced off-chain,
+// the on-chain verifier cannot read the time.
+func (c *PaymentChannel) LockPayment(ctx context.Context, asset channel.Asset, amount channel.Bal, hashlock [32]byte, timeout time.Time) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("lock amount must be positive")
+	}
+	if !timeout.After(time.Now()) {
+		return errors.New("lock timeout must be in the future")
+	}
+	a, ok := c.ch.State().AssetIndex(asset)
+	if !ok {
+		return fmt.Errorf("asset %v not in channel", asset)
+	}
+	if _, err := htlcData(c.ch.State()); err != nil {
+		return err
+	}
+	lock := htlc.Lock{
+		Hashlock: hashlock,
+		Sender:   uint16(c.ch.Idx()),
+		Receiver: uint16(c.onlyPeer()),
+		Asset:    uint16(a),
+		Amount:   new(big.Int).Set(amount),
+		Timeout:  uint64(timeout.Unix()),
+	}
+	return c.ch.Update(ctx, func(state *channel.State) {
+		data := state.Data.(*htlc.Data)
+		data.Locks = append(data.Locks, lock)
+		data.Preimage = [32]byte{}
+	})
+}
+
+// ClaimPayment claims the amount locked for us to the hash of the preimage. It
+// fails once the timeout of the lock has passed.
+func (c *PaymentChannel) ClaimPayment(ctx context.Context, preimage [32]byte) error {
+	hashlock := htlc.Hash(preimage)
+	i, err := c.findLock(func(l htlc.Lock) bool {
+		return l.Hashlock == hashlock && l.Receiver == uint16(c.ch.Idx())
+	})
+	if err != nil {
+		return err
+	}
+	return c.ch.Update(ctx, func(state *channel.State) {
+		data := state.Data.(*htlc.Data)
+		l := data.Locks[i]
+		bals := state.Balances[l.Asset]
+		bals[l.Sender].Sub(bals[l.Sender], l.Amount)
+		bals[l.Receiver].Add(bals[l.Receiver], l.Amount)
+		data.Locks = append(data.Locks[:i], data.Locks[i+1:]...)
+		data.Preimage = preimage
+	})
+}
+
+// ReleasePayment gives back an amount locked for us to the hashlock to its
+// sender, e.g. because we cannot claim it before its timeout.
+func (c *PaymentChannel) ReleasePayment(ctx context.Context, hashlock [32]byte) error {
+	i, err := c.findLock(func(l htlc.Lock) bool {
+		return l.Hashlock == hashlock && l.Receiver == uint16(c.ch.Idx())
+	})
+	if err != nil {
+		return err
+	}
+	return c.ch.Update(ctx, func(state *channel.State) {
+		data := state.Data.(*htlc.Data)
+		data.Locks = append(data.Locks[:i], data.Locks[i+1:]...)
+		data.Preimage = [32]byte{}
+	})
+}
+
+// RefundPayment takes back an amount we locked to the hashlock whose timeout
+// has passed without the peer claiming or releasing it.
+func (c *PaymentChannel) RefundPayment(ctx context.Context, hashlock [32]byte) error {
+	now := uint64(time.Now().Unix())
+	i, err := c.findLock(func(l htlc.Lock) bool {
+		return l.Hashlock == hashlock && l.Sender == uint16(c.ch.Idx()) && l.Timeout < now
+	})
+	if err != nil {
+		return err
+	}
+	return c.ch.Update(ctx, func(state *channel.State) {
+		data := state.Data.(*htlc.Data)
+		data.Locks = append(data.Locks[:i], data.Locks[i+1:]...)
+		data.Preimage = [32]byte{}
+	})
+}
+
+// findLock returns the index of the first lock of the channel that matches.
+func (c *PaymentChannel) findLock(match func(htlc.Lock) bool) (int, error) {
+	data, err := htlcData(c.ch.State())
+	if err != nil {
+		return 0, err
+	}
+	for i, l := range data.Locks {
+		if match(l) {
+			return i, nil
+		}
+	}
+	return 0, errors.New("no matching lock")
+}
+
+func htlcData(state *channel.State) (*htlc.Data, error) {
+	data, ok := state.Data.(*htlc.Data)
+	if !ok {
+		return nil, errors.New("not an HTLC channel")
+	}
+	return data, nil
+}
@@ -0,0 +1,525 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+	"perun.network/go-perun/wire/perunio"
+	"perun.network/sol-eth-cross-chain-demo/htlc"
+)
+
+const (
+	forwardUpdateTimeout = 30 * time.Second
+
+	// forwardLockMargin is how much earlier our lock for the recipient times
+	// out than the sender's lock for us, so that we can still claim the
+	// sender's lock after learning the preimage. The sender's lock must time
+	// out at least twice the margin from now.
+	forwardLockMargin = 10 * time.Minute
+
+	ethBackend wallet.BackendID = 1
+)
+
+func init() {
+	wire.RegisterExternalDecoder(forwardMsgType, func(r io.Reader) (wire.Msg, error) {
+		var m ForwardMsg
+		return &m, m.Decode(r)
+	}, "Forward")
+}
+
+// HubConfig enables hub mode, in which the client forwards payments it
+// receives in one channel to the recipient's channel with the client.
+type HubConfig struct {
+	Routes []HubRoute
+}
+
+// HubRoute is a conversion between the currencies of two chains that the hub
+// offers. A route from a chain to itself forwards the same currency. Forwards
+// are made atomic with the HTLC app, which only supports channels on
+// Ethereum, so only routes from Ethereum to Ethereum are allowed.
+type HubRoute struct {
+	From, To wallet.BackendID // Chains of the received and the forwarded currency.
+	Rate     *big.Rat         // Amount of To forwarded per unit of From, after the fee. Nil means 1.
+	Fee      *big.Rat         // Share of the received amount kept by the hub. Nil means none.
+}
+
+// output returns the amount forwarded for the received amount and the fee.
+func (r HubRoute) output(amount *big.Int) (out, fee *big.Int) {
+	feeAmount := new(big.Rat).SetInt(amount)
+	if r.Fee != nil {
+		feeAmount.Mul(feeAmount, r.Fee)
+	} else {
+		feeAmount.SetInt64(0)
+	}
+	fee = new(big.Int).Quo(feeAmount.Num(), feeAmount.Denom())
+	o := new(big.Rat).SetInt(new(big.Int).Sub(amount, fee))
+	if r.Rate != nil {
+		o.Mul(o, r.Rate)
+	}
+	return new(big.Int).Quo(o.Num(), o.Denom()), fee
+}
+
+// ForwardMsg announces that the channel update to the given version pays the
+// hub to forward the payment to the recipient. It is sent before the update.
+type ForwardMsg struct {
+	ChannelID channel.ID
+	Version   uint64
+	Asset     uint16 // Index of the paid asset in the channel.
+	Amount    *big.Int
+	Recipient map[wallet.BackendID]wire.Address
+	Out       uint32   // Chain of the currency forwarded to the recipient.
+	MinOut    *big.Int // Minimum forwarded amount.
+}
+
+// Type implements wire.Msg.
+func (*ForwardMsg) Type() wire.Type { return forwardMsgType }
+
+// Encode implements wire.Msg.
+func (m *ForwardMsg) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ChannelID, m.Version, m.Asset, m.Amount,
+		wire.AddressDecMap(m.Recipient), m.Out, m.MinOut)
+}
+
+// Decode decodes a ForwardMsg.
+func (m *ForwardMsg) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.ChannelID, &m.Version, &m.Asset, &m.Amount,
+		(*wire.AddressDecMap)(&m.Recipient), &m.Out, &m.MinOut)
+}
+
+// HubLiquidity is the liquidity of the hub in the currency of a chain.
+type HubLiquidity struct {
+	Backend   wallet.BackendID
+	Balance   channel.Bal // Our balance in all open channels.
+	Received  channel.Bal // Total received for forwarding.
+	Forwarded channel.Bal // Total forwarded.
+	Fees      channel.Bal // Total fees kept, part of Received.
+}
+
+// hub forwards payments between the channels of the client.
+type hub struct {
+	routes []HubRoute         // Nil if the client is not a hub.
+	ctx    context.Context    // Canceled on shutdown.
+	stop   context.CancelFunc // Stops the pending forwards.
+
+	mu        sync.Mutex
+	announced map[channel.ID]map[uint64]*ForwardMsg
+	pending   map[[32]byte]*forward // Forwards by hashlock until resolved.
+	flows     map[wallet.BackendID]*HubLiquidity
+}
+
+func newHub(cfg *HubConfig) *hub {
+	ctx, stop := context.WithCancel(context.Background())
+	h := &hub{
+		ctx:       ctx,
+		stop:      stop,
+		announced: make(map[channel.ID]map[uint64]*ForwardMsg),
+		pending:   make(map[[32]byte]*forward),
+		flows:     make(map[wallet.BackendID]*HubLiquidity),
+	}
+	if cfg != nil {
+		h.routes = cfg.Routes
+	}
+	return h
+}
+
+// checkRoutes rejects routes whose forwards cannot be made atomic.
+func (cfg *HubConfig) checkRoutes() error {
+	for _, r := range cfg.Routes {
+		if r.From != ethBackend || r.To != ethBackend {
+			return fmt.Errorf("route from chain %d to chain %d cannot be made atomic: conditional payments only work on Ethereum", r.From, r.To)
+		}
+	}
+	return nil
+}
+
+func (h *hub) route(from, to wallet.BackendID) (HubRoute, bool) {
+	for _, r := range h.routes {
+		if r.From == from && r.To == to {
+			return r, true
+		}
+	}
+	return HubRoute{}, false
+}
+
+// flow returns the liquidity record of the chain. It must be called with the
+// lock held.
+func (h *hub) flow(b wallet.BackendID) *HubLiquidity {
+	f, ok := h.flows[b]
+	if !ok {
+		f = &HubLiquidity{Backend: b, Received: new(big.Int), Forwarded: new(big.Int), Fees: new(big.Int)}
+		h.flows[b] = f
+	}
+	return f
+}
+
+// Forward pays amount of the asset to the hub peer of this channel, which
+// forwards it, converted to the currency of the out chain, to the recipient
+// through its channel with the recipient. The payment is a lock to the
+// hashlock with the HTLC app, and the hub locks the converted amount for the
+// recipient to the same hashlock with an earlier timeout. When the recipient
+// claims it with the preimage, the hub learns the preimage and claims our
+// lock, so either both payments complete or neither does. Both channels must
+// be HTLC channels, which only exist on Ethereum. The payment fails if the
+// recipient would get less than minOut; if the hub cannot forward it, the
+// hub releases our lock, otherwise we can refund it after the timeout.
+func (c *PaymentChannel) Forward(ctx context.Context, recipient map[wallet.BackendID]wire.Address, asset channel.Asset, amount channel.Bal, out wallet.BackendID, minOut channel.Bal, hashlock [32]byte, timeout time.Time) error {
+	a, ok := c.ch.State().AssetIndex(asset)
+	if !ok {
+		return fmt.Errorf("asset %v not in channel", asset)
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("forwarded amount must be positive")
+	}
+	if _, err := htlcData(c.ch.State()); err != nil {
+		return err
+	}
+	if minOut == nil {
+		minOut = new(big.Int)
+	}
+	hub := c.onlyPeer()
+
+	// Announce the payment first, so that the hub can match the update.
+	msg := &ForwardMsg{
+		ChannelID: c.ch.ID(),
+		Version:   c.ch.State().Version + 1,
+		Asset:     uint16(a),
+		Amount:    new(big.Int).Set(amount),
+		Recipient: recipient,
+		Out:       uint32(out),
+		MinOut:    minOut,
+	}
+	if err := c.client.router.send(ctx, msg, c.client.waddress, c.ch.Peers()[hub]); err != nil {
+		return fmt.Errorf("announcing forward: %w", err)
+	}
+	if err := c.LockPayment(ctx, asset, amount, hashlock, timeout); err != nil {
+		return fmt.Errorf("forwarding payment: %w", err)
+	}
+	return nil
+}
+
+// HubLiquidity returns our balance and the forwarded volume per chain.
+func (c *PaymentClient) HubLiquidity() []HubLiquidity {
+	balances := make(map[wallet.BackendID]*big.Int)
+	for _, ch := range c.channelList() {
+		state := ch.State()
+		for a, asset := range state.Assets {
+			b := backendOf(asset)
+			if balances[b] == nil {
+				balances[b] = new(big.Int)
+			}
+			balances[b].Add(balances[b], state.Balances[a][ch.Idx()])
+		}
+	}
+
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	liquidity := make([]HubLiquidity, 0, len(c.currency))
+	for _, asset := range c.currency {
+		b := backendOf(asset)
+		f := *h.flow(b)
+		f.Balance = balances[b]
+		if f.Balance == nil {
+			f.Balance = new(big.Int)
+		}
+		f.Received = new(big.Int).Set(f.Received)
+		f.Forwarded = new(big.Int).Set(f.Forwarded)
+		f.Fees = new(big.Int).Set(f.Fees)
+		liquidity = append(liquidity, f)
+	}
+	return liquidity
+}
+
+// channelList returns the open channels.
+func (c *PaymentClient) channelList() []*client.Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	chs := make([]*client.Channel, 0, len(c.openChannels))
+	for _, ch := range c.openChannels {
+		chs = append(chs, ch)
+	}
+	return chs
+}
+
+// handleForward records an announced forward from the peer of the channel,
+// so that the channel update can be matched.
+func (c *PaymentClient) handleForward(e *wire.Envelope) {
+	msg, ok := e.Msg.(*ForwardMsg)
+	if !ok {
+		return
+	}
+	ch, err := c.perunClient.Channel(msg.ChannelID)
+	if err != nil || !channel.EqualWireMaps(ch.Peers()[1-ch.Idx()], e.Sender) {
+		return
+	}
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.announced[msg.ChannelID] == nil {
+		h.announced[msg.ChannelID] = make(map[uint64]*ForwardMsg)
+	}
+	h.announced[msg.ChannelID][msg.Version] = msg
+}
+
+// forward is a payment forwarded by the hub: the sender's lock for us in the
+// incoming channel and our lock for the recipient in the outgoing channel,
+// both to the same hashlock.
+type forward struct {
+	hashlock [32]byte
+	in, out  *client.Channel
+	asset    channel.Asset // Asset of our lock in the outgoing channel.
+	from, to wallet.BackendID
+	received *big.Int      // Amount of the sender's lock.
+	amount   *big.Int      // Amount of our lock.
+	fee      *big.Int      // Part of the received amount kept by us.
+	timeout  time.Time     // Timeout of our lock.
+	done     chan struct{} // Closed once our lock is claimed or released.
+}
+
+// checkForward checks an incoming update that was announced to be forwarded.
+// It must lock the announced amount for us, and we must be able to lock the
+// converted amount for the recipient with an earlier timeout. The returned
+// forward is started once the update is accepted.
+func (c *PaymentClient) checkForward(cur, next *channel.State, idx channel.Index) (*forward, error) {
+	h := c.hub
+	h.mu.Lock()
+	msg, ok := h.announced[next.ID][next.Version]
+	delete(h.announced[next.ID], next.Version)
+	h.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	if h.routes == nil {
+		return nil, errors.New("not a hub")
+	}
+
+	l, err := addedLock(cur, next)
+	if err != nil {
+		return nil, err
+	}
+	if l.Receiver != uint16(idx) || l.Asset != msg.Asset || l.Amount.Cmp(msg.Amount) != 0 {
+		return nil, errors.New("lock does not match forwarded payment")
+	}
+	from, to := backendOf(next.Assets[msg.Asset]), wallet.BackendID(msg.Out)
+	route, ok := h.route(from, to)
+	if !ok {
+		return nil, fmt.Errorf("no route from chain %d to chain %d", from, to)
+	}
+	out, fee := route.output(l.Amount)
+	if out.Sign() <= 0 || out.Cmp(msg.MinOut) < 0 {
+		return nil, fmt.Errorf("forwarded amount %v below minimum %v", out, msg.MinOut)
+	}
+	timeout := time.Unix(int64(l.Timeout), 0).Add(-forwardLockMargin)
+	if time.Until(timeout) < forwardLockMargin {
+		return nil, errors.New("lock timeout too close to forward the payment")
+	}
+
+	in, err := c.perunClient.Channel(next.ID)
+	if err != nil {
+		return nil, err
+	}
+	ch, asset, err := c.forwardChannel(msg.Recipient, to, out)
+	if err != nil {
+		return nil, err
+	}
+	f := &forward{
+		hashlock: l.Hashlock,
+		in:       in,
+		out:      ch,
+		asset:    asset,
+		from:     from,
+		to:       to,
+		received: l.Amount,
+		amount:   out,
+		fee:      fee,
+		timeout:  timeout,
+		done:     make(chan struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.pending[f.hashlock]; ok {
+		return nil, errors.New("hashlock already forwarded")
+	}
+	h.pending[f.hashlock] = f
+	return f, nil
+}
+
+// addedLock returns the lock added by an update of an HTLC channel.
+func addedLock(cur, next *channel.State) (htlc.Lock, error) {
+	from, ok := cur.Data.(*htlc.Data)
+	to, ok2 := next.Data.(*htlc.Data)
+	if !ok || !ok2 || len(to.Locks) != len(from.Locks)+1 {
+		return htlc.Lock{}, errors.New("forwarded payment is not a lock")
+	}
+	// The app only allows appending a lock.
+	return to.Locks[len(to.Locks)-1], nil
+}
+
+// runForward locks the forwarded amount for the recipient after we accepted
+// the sender's lock. If that fails, or our lock times out without being
+// claimed, the sender's lock is released.
+func (c *PaymentClient) runForward(f *forward) {
+	h := c.hub
+	ctx, cancel := context.WithTimeout(h.ctx, forwardUpdateTimeout)
+	err := newPaymentChannel(f.out, c).LockPayment(ctx, f.asset, f.amount, f.hashlock, f.timeout)
+	cancel()
+	if err != nil {
+		// The update may have been signed by the recipient despite the error.
+		if data, _ := htlcData(f.out.State()); data == nil || !hasLock(data, f.hashlock) {
+			log.Printf("Forwarding to recipient: %v", err)
+			if h.take(f) {
+				c.resolveForward(f, false, [32]byte{})
+			}
+			return
+		}
+	}
+
+	// The recipient claims or releases our lock in a channel update.
+	select {
+	case <-f.done:
+		return
+	case <-h.ctx.Done():
+		return
+	case <-time.After(time.Until(f.timeout) + time.Second):
+	}
+	ctx, cancel = context.WithTimeout(h.ctx, forwardUpdateTimeout)
+	defer cancel()
+	if err := newPaymentChannel(f.out, c).RefundPayment(ctx, f.hashlock); err != nil {
+		log.Printf("Refunding expired forward: %v", err)
+		return
+	}
+	if h.take(f) {
+		c.resolveForward(f, false, [32]byte{})
+	}
+}
+
+// forwardsResolved resolves the forwards whose lock for the recipient was
+// removed by an accepted update of the outgoing channel.
+func (c *PaymentClient) forwardsResolved(cur, next *channel.State) {
+	from, ok := cur.Data.(*htlc.Data)
+	to, ok2 := next.Data.(*htlc.Data)
+	if !ok || !ok2 {
+		return
+	}
+	h := c.hub
+	h.mu.Lock()
+	var resolved []*forward
+	for _, f := range h.pending {
+		if f.out.ID() == next.ID && hasLock(from, f.hashlock) && !hasLock(to, f.hashlock) {
+			resolved = append(resolved, f)
+		}
+	}
+	h.mu.Unlock()
+	for _, f := range resolved {
+		if h.take(f) {
+			go c.resolveForward(f, htlc.Hash(to.Preimage) == f.hashlock, to.Preimage)
+		}
+	}
+}
+
+// resolveForward claims the sender's lock with the preimage revealed by the
+// recipient, or releases it if the recipient did not claim our lock.
+func (c *PaymentClient) resolveForward(f *forward, claimed bool, preimage [32]byte) {
+	ctx, cancel := context.WithTimeout(c.hub.ctx, forwardUpdateTimeout)
+	defer cancel()
+	in := newPaymentChannel(f.in, c)
+	if !claimed {
+		if err := in.ReleasePayment(ctx, f.hashlock); err != nil {
+			log.Printf("Releasing forwarded payment: %v", err)
+		}
+		return
+	}
+	if err := in.ClaimPayment(ctx, preimage); err != nil {
+		log.Printf("Claiming forwarded payment: %v", err)
+		return
+	}
+	log.Printf("Forwarded %v on chain %d as %v on chain %d", f.received, f.from, f.amount, f.to)
+
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.flow(f.from).Received.Add(h.flow(f.from).Received, f.received)
+	h.flow(f.from).Fees.Add(h.flow(f.from).Fees, f.fee)
+	h.flow(f.to).Forwarded.Add(h.flow(f.to).Forwarded, f.amount)
+}
+
+// take removes a pending forward, so that it is resolved only once. It
+// returns false if the forward was already taken.
+func (h *hub) take(f *forward) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pending[f.hashlock] != f {
+		return false
+	}
+	delete(h.pending, f.hashlock)
+	close(f.done)
+	return true
+}
+
+func hasLock(data *htlc.Data, hashlock [32]byte) bool {
+	for _, l := range data.Locks {
+		if l.Hashlock == hashlock {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardChannel returns an open HTLC channel with the recipient in which our
+// balance of the currency of the chain, less our locks, covers the amount.
+func (c *PaymentClient) forwardChannel(recipient map[wallet.BackendID]wire.Address, b wallet.BackendID, amount *big.Int) (*client.Channel, channel.Asset, error) {
+	found := false
+	for _, ch := range c.channelList() {
+		if !channel.EqualWireMaps(ch.Peers()[1-ch.Idx()], recipient) {
+			continue
+		}
+		state := ch.State()
+		data, ok := state.Data.(*htlc.Data)
+		if !ok {
+			continue
+		}
+		found = true
+		for a, asset := range state.Assets {
+			if backendOf(asset) != b || state.IsFinal {
+				continue
+			}
+			free := new(big.Int).Set(state.Balances[a][ch.Idx()])
+			for _, l := range data.Locks {
+				if int(l.Asset) == a && l.Sender == uint16(ch.Idx()) {
+					free.Sub(free, l.Amount)
+				}
+			}
+			if free.Cmp(amount) >= 0 {
+				return ch, asset, nil
+			}
+		}
+	}
+	if !found {
+		return nil, nil, errors.New("no HTLC channel with recipient")
+	}
+	return nil, nil, errors.New("insufficient hub liquidity")
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestHubRouteOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    HubRoute
+		amount   int64
+		out, fee int64
+	}{
+		{"same currency", HubRoute{}, 100, 100, 0},
+		{"fee", HubRoute{Fee: big.NewRat(1, 100)}, 1000, 990, 10},
+		{"fee rounded down", HubRoute{Fee: big.NewRat(1, 100)}, 150, 149, 1},
+		{"rate", HubRoute{Rate: big.NewRat(3, 2)}, 100, 150, 0},
+		{"rate rounded down", HubRoute{Rate: big.NewRat(1, 3)}, 100, 33, 0},
+		{"rate after fee", HubRoute{Rate: big.NewRat(2, 1), Fee: big.NewRat(1, 10)}, 100, 180, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, fee := tt.route.output(big.NewInt(tt.amount))
+			if out.Int64() != tt.out || fee.Int64() != tt.fee {
+				t.Fatalf("output(%d) = %v, %v; want %d, %d", tt.amount, out, fee, tt.out, tt.fee)
+			}
+		})
+	}
+}
+
+func TestHubCheckRoutes(t *testing.T) {
+	if err := (&HubConfig{Routes: []HubRoute{{From: 1, To: 1}}}).checkRoutes(); err != nil {
+		t.Fatalf("Ethereum route rejected: %v", err)
+	}
+	for _, r := range []HubRoute{{From: 1, To: 6}, {From: 6, To: 1}, {From: 6, To: 6}} {
+		if err := (&HubConfig{Routes: []HubRoute{r}}).checkRoutes(); err == nil {
+			t.Errorf("route from %d to %d accepted", r.From, r.To)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire"
+	"perun.network/go-perun/wire/perunio"
+)
+
+func init() {
+	wire.RegisterExternalDecoder(invoiceMsgType, func(r io.Reader) (wire.Msg, error) {
+		var m InvoiceMsg
+		return &m, m.Decode(r)
+	}, "Invoice")
+	wire.RegisterExternalDecoder(invoicePaymentMsgType, func(r io.Reader) (wire.Msg, error) {
+		var m InvoicePaymentMsg
+		return &m, m.Decode(r)
+	}, "InvoicePayment")
+}
+
+// InvoiceID identifies an invoice.
+type InvoiceID = [32]byte
+
+// InvoiceStatus is the status of an invoice.
+type InvoiceStatus int
+
+// Invoice statuses.
+const (
+	InvoiceOpen     InvoiceStatus = iota // Waiting for payment.
+	InvoicePaid                          // Paid with a channel update.
+	InvoiceExpired                       // Not paid before its expiry.
+	InvoiceRejected                      // The invoice was invalid.
+	InvoicePaying                        // The payer is sending the payment.
+)
+
+// String returns the name of the invoice status.
+func (s InvoiceStatus) String() string {
+	switch s {
+	case InvoiceOpen:
+		return "open"
+	case InvoicePaid:
+		return "paid"
+	case InvoiceExpired:
+		return "expired"
+	case InvoiceRejected:
+		return "rejected"
+	case InvoicePaying:
+		return "paying"
+	default:
+		return "unknown"
+	}
+}
+
+// Invoice is a payment request from the payee to the payer of a channel.
+type Invoice struct {
+	ID          InvoiceID
+	ChannelID   channel.ID
+	Asset       int // Index of the asset in the channel.
+	Amount      channel.Bal
+	Memo        string
+	Expiry      time.Time
+	Issued      bool // Whether we are the payee.
+	Status      InvoiceStatus
+	PaidVersion uint64 // Version of the channel state that paid the invoice.
+	Reason      string // Why the invoice was rejected.
+}
+
+// InvoiceMsg sends an invoice to the payer.
+type InvoiceMsg struct {
+	ID        InvoiceID
+	ChannelID channel.ID
+	Asset     uint16
+	Amount    *big.Int
+	Memo      string
+	Expiry    time.Time
+}
+
+// Type implements wire.Msg.
+func (*InvoiceMsg) Type() wire.Type { return invoiceMsgType }
+
+// Encode implements wire.Msg.
+func (m *InvoiceMsg) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ID, m.ChannelID, m.Asset, m.Amount, m.Memo, m.Expiry)
+}
+
+// Decode decodes an InvoiceMsg.
+func (m *InvoiceMsg) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.ID, &m.ChannelID, &m.Asset, &m.Amount, &m.Memo, &m.Expiry)
+}
+
+// InvoicePaymentMsg announces that the channel update to the given version
+// paid an invoice. It is sent after the update, so that only updates the
+// payee accepted are announced.
+type InvoicePaymentMsg struct {
+	ID        InvoiceID
+	ChannelID channel.ID
+	Version   uint64
+}
+
+// Type implements wire.Msg.
+func (*InvoicePaymentMsg) Type() wire.Type { return invoicePaymentMsgType }
+
+// Encode implements wire.Msg.
+func (m *InvoicePaymentMsg) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ID, m.ChannelID, m.Version)
+}
+
+// Decode decodes an InvoicePaymentMsg.
+func (m *InvoicePaymentMsg) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.ID, &m.ChannelID, &m.Version)
+}
+
+// maxUnmatchedPayments is the number of received payments per channel that
+// are kept for matching them with invoices announced afterwards.
+const maxUnmatchedPayments = 16
+
+// receivedPayment is an accepted update in which we received funds.
+type receivedPayment struct {
+	amounts []channel.Bal // Received amount per asset.
+	at      time.Time
+}
+
+// invoiceLedger keeps the invoices we issued and received.
+type invoiceLedger struct {
+	mu       sync.Mutex
+	invoices map[InvoiceID]*Invoice
+	payments map[channel.ID]map[uint64]receivedPayment // Unmatched payments per channel and version.
+}
+
+func newInvoiceLedger() *invoiceLedger {
+	return &invoiceLedger{
+		invoices: make(map[InvoiceID]*Invoice),
+		payments: make(map[channel.ID]map[uint64]receivedPayment),
+	}
+}
+
+// expire marks the invoice as expired if it is open past its expiry. It
+// must be called with the lock held.
+func (l *invoiceLedger) expire(inv *Invoice, now time.Time) {
+	if inv.Status == InvoiceOpen && now.After(inv.Expiry) {
+		inv.Status = InvoiceExpired
+	}
+}
+
+// Invoices returns all invoices we issued or received, oldest expiry first.
+func (c *PaymentClient) Invoices() []Invoice {
+	l := c.invoices
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	invoices := make([]Invoice, 0, len(l.invoices))
+	for _, inv := range l.invoices {
+		l.expire(inv, now)
+		invoices = append(invoices, *inv)
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].Expiry.Before(invoices[j].Expiry) })
+	return invoices
+}
+
+// Invoice returns the invoice with the given ID.
+func (c *PaymentClient) Invoice(id InvoiceID) (Invoice, bool) {
+	l := c.invoices
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	inv, ok := l.invoices[id]
+	if !ok {
+		return Invoice{}, false
+	}
+	l.expire(inv, time.Now())
+	return *inv, true
+}
+
+// RequestPayment issues an invoice over amount of the asset, which the peer
+// has to pay within ttl, and sends it to the peer.
+func (c *PaymentChannel) RequestPayment(ctx context.Context, asset channel.Asset, amount channel.Bal, memo string, ttl time.Duration) (Invoice, error) {
+	a, ok := c.ch.State().AssetIndex(asset)
+	if !ok {
+		return Invoice{}, fmt.Errorf("asset %v not in channel", asset)
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return Invoice{}, errors.New("invoice amount must be positive")
+	}
+	inv := &Invoice{
+		ChannelID: c.ch.ID(),
+		Asset:     int(a),
+		Amount:    new(big.Int).Set(amount),
+		Memo:      memo,
+		Expiry:    time.Now().Add(ttl),
+		Issued:    true,
+	}
+	if _, err := rand.Read(inv.ID[:]); err != nil {
+		return Invoice{}, fmt.Errorf("generating invoice ID: %w", err)
+	}
+
+	l := c.client.invoices
+	l.mu.Lock()
+	l.invoices[inv.ID] = inv
+	l.mu.Unlock()
+
+	msg := &InvoiceMsg{
+		ID:        inv.ID,
+		ChannelID: inv.ChannelID,
+		Asset:     uint16(a),
+		Amount:    inv.Amount,
+		Memo:      memo,
+		Expiry:    inv.Expiry,
+	}
+	if err := c.client.router.send(ctx, msg, c.client.waddress, c.ch.Peers()[c.onlyPeer()]); err != nil {
+		return Invoice{}, fmt.Errorf("sending invoice: %w", err)
+	}
+	return *inv, nil
+}
+
+// PayInvoice pays a received invoice with a channel update and then announces
+// the payment to the payee. The invoice is marked as paying meanwhile, so
+// that it is not paid twice.
+func (c *PaymentClient) PayInvoice(ctx context.Context, id InvoiceID) error {
+	l := c.invoices
+	l.mu.Lock()
+	inv, ok := l.invoices[id]
+	if !ok || inv.Issued {
+		l.mu.Unlock()
+		return errors.New("unknown invoice")
+	}
+	l.expire(inv, time.Now())
+	if inv.Status != InvoiceOpen {
+		l.mu.Unlock()
+		return fmt.Errorf("invoice is %v", inv.Status)
+	}
+	inv.Status = InvoicePaying
+	pay := *inv
+	l.mu.Unlock()
+
+	version, err := c.payInvoice(ctx, pay)
+	l.mu.Lock()
+	if err != nil {
+		inv.Status = InvoiceOpen
+		l.mu.Unlock()
+		return err
+	}
+	inv.Status = InvoicePaid
+	inv.PaidVersion = version
+	paid := *inv
+	l.mu.Unlock()
+	c.events.publish(Event{Type: InvoiceSettled, ChannelID: paid.ChannelID, Version: version, Invoice: &paid})
+
+	ch, err := c.perunClient.Channel(pay.ChannelID)
+	if err != nil {
+		return fmt.Errorf("unknown channel: %w", err)
+	}
+	msg := &InvoicePaymentMsg{ID: id, ChannelID: pay.ChannelID, Version: version}
+	if err := c.router.send(ctx, msg, c.waddress, ch.Peers()[1-ch.Idx()]); err != nil {
+		return fmt.Errorf("invoice paid, but announcing the payment: %w", err)
+	}
+	return nil
+}
+
+// payInvoice transfers the amount of the invoice to the payee and returns the
+// version of the channel state that paid it.
+func (c *PaymentClient) payInvoice(ctx context.Context, pay Invoice) (uint64, error) {
+	ch, err := c.perunClient.Channel(pay.ChannelID)
+	if err != nil {
+		return 0, fmt.Errorf("unknown channel: %w", err)
+	}
+	payee := 1 - ch.Idx()
+	var version uint64
+	err = ch.Update(ctx, func(state *channel.State) {
+		state.Allocation.TransferBalance(ch.Idx(), payee, state.Assets[pay.Asset], pay.Amount)
+		version = state.Version + 1 // Incremented after the update function.
+	})
+	if err != nil {
+		return 0, fmt.Errorf("paying invoice: %w", err)
+	}
+	return version, nil
+}
+
+// handleInvoice validates and records a received invoice.
+func (c *PaymentClient) handleInvoice(e *wire.Envelope) {
+	msg, ok := e.Msg.(*InvoiceMsg)
+	if !ok {
+		return
+	}
+	// Invoices that do not come from the peer of one of our channels are
+	// dropped rather than recorded as rejected.
+	ch, err := c.perunClient.Channel(msg.ChannelID)
+	if err != nil || !channel.EqualWireMaps(ch.Peers()[1-ch.Idx()], e.Sender) {
+		return
+	}
+	inv := &Invoice{
+		ID:        msg.ID,
+		ChannelID: msg.ChannelID,
+		Asset:     int(msg.Asset),
+		Amount:    msg.Amount,
+		Memo:      msg.Memo,
+		Expiry:    msg.Expiry,
+	}
+	if err := checkInvoice(inv, ch.State(), ch.Idx()); err != nil {
+		log.Printf("Rejecting invoice %x: %v", msg.ID, err)
+		inv.Status = InvoiceRejected
+		inv.Reason = err.Error()
+	}
+
+	l := c.invoices
+	l.mu.Lock()
+	if _, dup := l.invoices[inv.ID]; dup {
+		l.mu.Unlock()
+		return
+	}
+	l.invoices[inv.ID] = inv
+	received := *inv
+	l.mu.Unlock()
+	c.events.publish(Event{Type: InvoiceReceived, ChannelID: inv.ChannelID, Invoice: &received})
+}
+
+// checkInvoice checks that the invoice has not expired and that we can pay it
+// in the channel state, in which we have the given index.
+func checkInvoice(inv *Invoice, state *channel.State, idx channel.Index) error {
+	switch {
+	case inv.Asset >= len(state.Assets):
+		return errors.New("unknown asset")
+	case inv.Amount == nil || inv.Amount.Sign() <= 0:
+		return errors.New("invalid amount")
+	case !time.Now().Before(inv.Expiry):
+		return errors.New("expired")
+	case state.Balances[inv.Asset][idx].Cmp(inv.Amount) < 0:
+		return errors.New("insufficient balance")
+	}
+	return nil
+}
+
+// handleInvoicePayment marks an invoice we issued as paid if the channel
+// peer announces a payment that we received and that matches it.
+func (c *PaymentClient) handleInvoicePayment(e *wire.Envelope) {
+	msg, ok := e.Msg.(*InvoicePaymentMsg)
+	if !ok {
+		return
+	}
+	ch, err := c.perunClient.Channel(msg.ChannelID)
+	if err != nil || !channel.EqualWireMaps(ch.Peers()[1-ch.Idx()], e.Sender) {
+		return
+	}
+	l := c.invoices
+	l.mu.Lock()
+	inv, ok := l.invoices[msg.ID]
+	if !ok || !inv.Issued || inv.ChannelID != msg.ChannelID {
+		l.mu.Unlock()
+		return
+	}
+	p, ok := l.payments[msg.ChannelID][msg.Version]
+	if !ok {
+		l.mu.Unlock()
+		log.Printf("Ignoring payment of invoice %x: no payment received in version %d", msg.ID, msg.Version)
+		return
+	}
+	l.expire(inv, p.at)
+	if inv.Status != InvoiceOpen || p.amounts[inv.Asset].Cmp(inv.Amount) != 0 {
+		l.mu.Unlock()
+		log.Printf("Ignoring payment of invoice %x: invoice is %v, received %v of %v", msg.ID, inv.Status, p.amounts[inv.Asset], inv.Amount)
+		return
+	}
+	delete(l.payments[msg.ChannelID], msg.Version)
+	inv.Status = InvoicePaid
+	inv.PaidVersion = msg.Version
+	paid := *inv
+	l.mu.Unlock()
+	c.events.publish(Event{Type: InvoiceSettled, ChannelID: paid.ChannelID, Version: msg.Version, Invoice: &paid})
+}
+
+// paymentReceived records an accepted update in which we received funds, so
+// that it can be matched with an invoice announced afterwards. Only the latest
+// maxUnmatchedPayments are kept per channel.
+func (c *PaymentClient) paymentReceived(cur, next *channel.State, idx channel.Index) {
+	p := receivedPayment{amounts: make([]channel.Bal, len(next.Assets)), at: time.Now()}
+	received := false
+	for a := range next.Assets {
+		p.amounts[a] = new(big.Int).Sub(next.Balances[a][idx], cur.Balances[a][idx])
+		received = received || p.amounts[a].Sign() > 0
+	}
+	if !received {
+		return
+	}
+
+	l := c.invoices
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	payments := l.payments[next.ID]
+	if payments == nil {
+		payments = make(map[uint64]receivedPayment)
+		l.payments[next.ID] = payments
+	}
+	payments[next.Version] = p
+	if len(payments) > maxUnmatchedPayments {
+		oldest := next.Version
+		for v := range payments {
+			if v < oldest {
+				oldest = v
+			}
+		}
+		delete(payments, oldest)
+	}
+}
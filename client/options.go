@@ -0,0 +1,130 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"math/big"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+	"perun.network/sol-eth-cross-chain-demo/oracle"
+)
+
+// Option configures optional behavior of a PaymentClient.
+type Option func(*options)
+
+// options holds the optional settings of a PaymentClient.
+type options struct {
+	gas            GasConfig
+	finality       FinalityConfig
+	persistenceDir string
+	historyDir     string
+	fundingTimeout FundingTimeouts
+	fundingLimits  map[wallet.BackendID]channel.Bal
+	hub            *HubConfig
+	oracle         oracle.Oracle
+	slippage       float64
+	solanaTxFee    uint64
+}
+
+func defaultOptions() options {
+	return options{
+		gas:         DefaultGasConfig(),
+		finality:    DefaultFinalityConfig(),
+		solanaTxFee: DefaultSolanaTxFee,
+		// By default, we only fund the Solana side of proposed channels.
+		fundingLimits: map[wallet.BackendID]channel.Bal{1: big.NewInt(0)},
+	}
+}
+
+func makeOptions(opts []Option) options {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithGasConfig sets the fee strategy, gas limits and resubmission policy of
+// the client's Ethereum transactions.
+func WithGasConfig(cfg GasConfig) Option {
+	return func(o *options) {
+		o.gas = cfg
+	}
+}
+
+// WithFinality sets when deposits on each chain are considered final.
+func WithFinality(cfg FinalityConfig) Option {
+	return func(o *options) {
+		o.finality = cfg
+	}
+}
+
+// WithPersistence stores the channels of the client in a database in the
+// given directory, so that they can be restored after a restart.
+func WithPersistence(dir string) Option {
+	return func(o *options) {
+		o.persistenceDir = dir
+	}
+}
+
+// WithHistory records every signed channel state in the history store in the
+// given directory.
+func WithHistory(dir string) Option {
+	return func(o *options) {
+		o.historyDir = dir
+	}
+}
+
+// WithFundingTimeouts bounds the funding on each chain.
+func WithFundingTimeouts(t FundingTimeouts) Option {
+	return func(o *options) {
+		o.fundingTimeout = t
+	}
+}
+
+// WithFundingLimits sets the maximum amount we deposit per chain into
+// channels proposed by peers. Chains without a limit are unbounded.
+func WithFundingLimits(limits map[wallet.BackendID]channel.Bal) Option {
+	return func(o *options) {
+		o.fundingLimits = limits
+	}
+}
+
+// WithHub enables hub mode, in which the client forwards payments between
+// its channels along the configured routes.
+func WithHub(cfg HubConfig) Option {
+	return func(o *options) {
+		o.hub = &cfg
+	}
+}
+
+// WithPriceOracle values swaps with the prices of the oracle. Incoming swaps
+// that pay us less than the value of what we give, minus the slippage
+// tolerance (e.g. 0.01 for 1%), are rejected.
+func WithPriceOracle(feed oracle.Oracle, slippage float64) Option {
+	return func(o *options) {
+		o.oracle = feed
+		o.slippage = slippage
+	}
+}
+
+// WithSolanaTxFee sets the fee in lamports that simulations estimate per
+// Solana transaction, e.g. the base fee plus the expected priority fee.
+func WithSolanaTxFee(lamports uint64) Option {
+	return func(o *options) {
+		o.solanaTxFee = lamports
+	}
+}
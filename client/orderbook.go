@@ -0,0 +1,578 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+	"perun.network/go-perun/wire/perunio"
+)
+
+func init() {
+	wire.RegisterExternalDecoder(orderMsgType, func(r io.Reader) (wire.Msg, error) {
+		var m OrderMsg
+		return &m, m.Decode(r)
+	}, "Order")
+	wire.RegisterExternalDecoder(orderCancelMsgType, func(r io.Reader) (wire.Msg, error) {
+		var m OrderCancelMsg
+		return &m, m.Decode(r)
+	}, "OrderCancel")
+	wire.RegisterExternalDecoder(orderFillMsgType, func(r io.Reader) (wire.Msg, error) {
+		var m OrderFillMsg
+		return &m, m.Decode(r)
+	}, "OrderFill")
+}
+
+// OrderID identifies an order.
+type OrderID = [32]byte
+
+// Order is a limit order of a channel participant to sell an amount of the
+// currency of one chain for that of another to the channel peer. It is signed
+// with the maker's Ethereum channel key.
+type Order struct {
+	ID        OrderID
+	ChannelID channel.ID
+	Maker     channel.Index    // Index of the maker in the channel.
+	Sell      wallet.BackendID // Chain of the sold currency.
+	Buy       wallet.BackendID // Chain of the bought currency.
+	Amount    channel.Bal      // Sold amount in the smallest unit.
+	Price     *big.Rat         // Smallest units of Buy per smallest unit of Sell.
+	Expiry    time.Time
+	Sig       wallet.Sig
+
+	Own    bool        // Whether we are the maker.
+	Filled channel.Bal // Amount of Sell filled so far.
+}
+
+// Remaining returns the amount of the order that is not yet filled.
+func (o *Order) Remaining() channel.Bal {
+	return new(big.Int).Sub(o.Amount, o.Filled)
+}
+
+// signedData returns the encoding of the signed fields of the order.
+func (o *Order) signedData() ([]byte, error) {
+	var buf bytes.Buffer
+	err := perunio.Encode(&buf, o.ID, o.ChannelID, uint16(o.Maker), uint32(o.Sell), uint32(o.Buy),
+		o.Amount, o.Price.Num(), o.Price.Denom(), o.Expiry)
+	return buf.Bytes(), err
+}
+
+func (o *Order) clone() Order {
+	c := *o
+	c.Amount = new(big.Int).Set(o.Amount)
+	c.Price = new(big.Rat).Set(o.Price)
+	c.Filled = new(big.Int).Set(o.Filled)
+	return c
+}
+
+// OrderMsg sends an order to the channel peer.
+type OrderMsg struct {
+	ID        OrderID
+	ChannelID channel.ID
+	Maker     uint16
+	Sell, Buy uint32
+	Amount    *big.Int
+	PriceNum  *big.Int
+	PriceDen  *big.Int
+	Expiry    time.Time
+	Sig       []byte
+}
+
+// Type implements wire.Msg.
+func (*OrderMsg) Type() wire.Type { return orderMsgType }
+
+// Encode implements wire.Msg.
+func (m *OrderMsg) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ID, m.ChannelID, m.Maker, m.Sell, m.Buy, m.Amount, m.PriceNum, m.PriceDen, m.Expiry,
+		uint16(len(m.Sig)), m.Sig)
+}
+
+// Decode decodes an OrderMsg.
+func (m *OrderMsg) Decode(r io.Reader) error {
+	var sigLen uint16
+	err := perunio.Decode(r, &m.ID, &m.ChannelID, &m.Maker, &m.Sell, &m.Buy, &m.Amount, &m.PriceNum, &m.PriceDen, &m.Expiry, &sigLen)
+	if err != nil {
+		return err
+	}
+	m.Sig = make([]byte, sigLen)
+	return perunio.Decode(r, &m.Sig)
+}
+
+// OrderCancelMsg withdraws an order of the sender.
+type OrderCancelMsg struct {
+	ID        OrderID
+	ChannelID channel.ID
+}
+
+// Type implements wire.Msg.
+func (*OrderCancelMsg) Type() wire.Type { return orderCancelMsgType }
+
+// Encode implements wire.Msg.
+func (m *OrderCancelMsg) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ID, m.ChannelID)
+}
+
+// Decode decodes an OrderCancelMsg.
+func (m *OrderCancelMsg) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.ID, &m.ChannelID)
+}
+
+// OrderFillMsg announces that the channel update to the given version fills
+// an amount of the recipient's order for the given payment. It is sent
+// before the update.
+type OrderFillMsg struct {
+	ID        OrderID
+	ChannelID channel.ID
+	Version   uint64
+	Amount    *big.Int // Filled amount of the sold currency.
+	Payment   *big.Int // Paid amount of the bought currency.
+}
+
+// Type implements wire.Msg.
+func (*OrderFillMsg) Type() wire.Type { return orderFillMsgType }
+
+// Encode implements wire.Msg.
+func (m *OrderFillMsg) Encode(w io.Writer) error {
+	return perunio.Encode(w, m.ID, m.ChannelID, m.Version, m.Amount, m.Payment)
+}
+
+// Decode decodes an OrderFillMsg.
+func (m *OrderFillMsg) Decode(r io.Reader) error {
+	return perunio.Decode(r, &m.ID, &m.ChannelID, &m.Version, &m.Amount, &m.Payment)
+}
+
+// orderBook keeps our orders and those of our peers.
+type orderBook struct {
+	mu     sync.Mutex
+	orders map[OrderID]*Order
+	fills  map[channel.ID]map[uint64]*OrderFillMsg // Announced fills per channel and version.
+}
+
+func newOrderBook() *orderBook {
+	return &orderBook{
+		orders: make(map[OrderID]*Order),
+		fills:  make(map[channel.ID]map[uint64]*OrderFillMsg),
+	}
+}
+
+// Orders returns the open orders of the channel, ours and the peer's, oldest
+// expiry first.
+func (c *PaymentClient) Orders(id channel.ID) []Order {
+	b := c.orders
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	var orders []Order
+	for _, o := range b.orders {
+		if o.ChannelID == id && now.Before(o.Expiry) && o.Remaining().Sign() > 0 {
+			orders = append(orders, o.clone())
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Expiry.Before(orders[j].Expiry) })
+	return orders
+}
+
+// PlaceOrder places a limit order to sell amount of the currency of chain
+// sell for that of chain buy at no less than price, in smallest units of buy
+// per smallest unit of sell. The order first fills the peer's matching
+// orders, best price first, each with a channel update. The rest is signed
+// and sent to the peer, which can fill it until the order expires after ttl.
+// It returns the resting order, whose amount is zero if the order was filled
+// completely.
+func (c *PaymentChannel) PlaceOrder(ctx context.Context, sell, buy wallet.BackendID, amount channel.Bal, price *big.Rat, ttl time.Duration) (Order, error) {
+	state := c.ch.State()
+	sellIdx, ok := assetOn(state, sell)
+	if !ok {
+		return Order{}, fmt.Errorf("no asset of chain %d in channel", sell)
+	}
+	buyIdx, ok := assetOn(state, buy)
+	if !ok || sell == buy {
+		return Order{}, fmt.Errorf("no other asset of chain %d in channel", buy)
+	}
+	switch {
+	case amount == nil || amount.Sign() <= 0:
+		return Order{}, errors.New("order amount must be positive")
+	case price == nil || price.Sign() <= 0:
+		return Order{}, errors.New("order price must be positive")
+	case state.Balances[sellIdx][c.ch.Idx()].Cmp(amount) < 0:
+		return Order{}, errors.New("insufficient balance")
+	}
+
+	o := &Order{
+		ChannelID: c.ch.ID(),
+		Maker:     c.ch.Idx(),
+		Sell:      sell,
+		Buy:       buy,
+		Price:     new(big.Rat).Set(price),
+		Own:       true,
+		Filled:    new(big.Int),
+	}
+	remaining := new(big.Int).Set(amount)
+	for _, r := range c.client.orders.matching(c.ch.ID(), sell, buy, price) {
+		// Buy as much of the resting order as we can pay at its price.
+		q := new(big.Rat).SetInt(remaining)
+		q.Quo(q, r.Price)
+		fill := new(big.Int).Quo(q.Num(), q.Denom())
+		if rem := r.Remaining(); rem.Cmp(fill) < 0 {
+			fill = rem
+		}
+		if bal := c.ch.State().Balances[buyIdx][r.Maker]; bal.Cmp(fill) < 0 {
+			fill = new(big.Int).Set(bal)
+		}
+		if fill.Sign() == 0 {
+			continue
+		}
+		payment := ceilMul(fill, r.Price)
+		if err := c.fillOrder(ctx, &r, fill, payment); err != nil {
+			return Order{}, fmt.Errorf("filling order %x: %w", r.ID, err)
+		}
+		remaining.Sub(remaining, payment)
+	}
+	o.Amount = remaining
+	if remaining.Sign() == 0 {
+		return *o, nil
+	}
+
+	o.Expiry = time.Now().Add(ttl)
+	if _, err := rand.Read(o.ID[:]); err != nil {
+		return Order{}, fmt.Errorf("generating order ID: %w", err)
+	}
+	data, err := o.signedData()
+	if err != nil {
+		return Order{}, fmt.Errorf("encoding order: %w", err)
+	}
+	acc, err := c.client.wallets[1].Unlock(c.client.account[1])
+	if err != nil {
+		return Order{}, fmt.Errorf("unlocking account: %w", err)
+	}
+	if o.Sig, err = acc.SignData(data); err != nil {
+		return Order{}, fmt.Errorf("signing order: %w", err)
+	}
+
+	b := c.client.orders
+	b.mu.Lock()
+	b.orders[o.ID] = o
+	placed := o.clone()
+	b.mu.Unlock()
+
+	msg := &OrderMsg{
+		ID:        o.ID,
+		ChannelID: o.ChannelID,
+		Maker:     uint16(o.Maker),
+		Sell:      uint32(o.Sell),
+		Buy:       uint32(o.Buy),
+		Amount:    o.Amount,
+		PriceNum:  o.Price.Num(),
+		PriceDen:  o.Price.Denom(),
+		Expiry:    o.Expiry,
+		Sig:       o.Sig,
+	}
+	if err := c.client.router.send(ctx, msg, c.client.waddress, c.ch.Peers()[c.onlyPeer()]); err != nil {
+		return placed, fmt.Errorf("sending order: %w", err)
+	}
+	return placed, nil
+}
+
+// CancelOrder withdraws one of our orders.
+func (c *PaymentChannel) CancelOrder(ctx context.Context, id OrderID) error {
+	b := c.client.orders
+	b.mu.Lock()
+	o, ok := b.orders[id]
+	if ok && o.Own && o.ChannelID == c.ch.ID() {
+		delete(b.orders, id)
+	}
+	b.mu.Unlock()
+	if !ok || !o.Own || o.ChannelID != c.ch.ID() {
+		return errors.New("unknown order")
+	}
+	msg := &OrderCancelMsg{ID: id, ChannelID: c.ch.ID()}
+	if err := c.client.router.send(ctx, msg, c.client.waddress, c.ch.Peers()[c.onlyPeer()]); err != nil {
+		return fmt.Errorf("sending cancellation: %w", err)
+	}
+	return nil
+}
+
+// matching returns the open orders of the peer in the channel that sell the
+// currency of chain buy for that of chain sell at a price that crosses ours,
+// best price first.
+func (b *orderBook) matching(id channel.ID, sell, buy wallet.BackendID, price *big.Rat) []Order {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	one := big.NewRat(1, 1)
+	var matches []Order
+	for _, o := range b.orders {
+		if o.Own || o.ChannelID != id || o.Sell != buy || o.Buy != sell || !now.Before(o.Expiry) {
+			continue
+		}
+		// We pay at most 1/price of sell per unit of buy.
+		if new(big.Rat).Mul(o.Price, price).Cmp(one) <= 0 && o.Remaining().Sign() > 0 {
+			matches = append(matches, o.clone())
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Price.Cmp(matches[j].Price) < 0 })
+	return matches
+}
+
+// fillOrder buys amount of the peer's order for the payment with a channel
+// update that is announced to the peer beforehand.
+func (c *PaymentChannel) fillOrder(ctx context.Context, o *Order, amount, payment *big.Int) error {
+	state := c.ch.State()
+	sold, _ := assetOn(state, o.Sell)
+	paid, _ := assetOn(state, o.Buy)
+	msg := &OrderFillMsg{ID: o.ID, ChannelID: o.ChannelID, Version: state.Version + 1, Amount: amount, Payment: payment}
+	if err := c.client.router.send(ctx, msg, c.client.waddress, c.ch.Peers()[o.Maker]); err != nil {
+		return fmt.Errorf("announcing fill: %w", err)
+	}
+	err := c.ch.Update(ctx, func(state *channel.State) {
+		state.Allocation.TransferBalance(o.Maker, c.ch.Idx(), state.Assets[sold], amount)
+		state.Allocation.TransferBalance(c.ch.Idx(), o.Maker, state.Assets[paid], payment)
+	})
+	if err != nil {
+		return err
+	}
+	c.client.orderFilled(o.ID, amount)
+	return nil
+}
+
+// orderFilled records a fill of an order.
+func (c *PaymentClient) orderFilled(id OrderID, amount *big.Int) {
+	b := c.orders
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if o, ok := b.orders[id]; ok {
+		o.Filled.Add(o.Filled, amount)
+	}
+}
+
+// handleOrder validates and records an order of the channel peer.
+func (c *PaymentClient) handleOrder(e *wire.Envelope) {
+	msg, ok := e.Msg.(*OrderMsg)
+	if !ok {
+		return
+	}
+	o := &Order{
+		ID:        msg.ID,
+		ChannelID: msg.ChannelID,
+		Maker:     channel.Index(msg.Maker),
+		Sell:      wallet.BackendID(msg.Sell),
+		Buy:       wallet.BackendID(msg.Buy),
+		Amount:    msg.Amount,
+		Expiry:    msg.Expiry,
+		Sig:       msg.Sig,
+		Filled:    new(big.Int),
+	}
+	if msg.PriceNum != nil && msg.PriceDen != nil && msg.PriceDen.Sign() > 0 {
+		o.Price = new(big.Rat).SetFrac(msg.PriceNum, msg.PriceDen)
+	}
+	if err := c.checkOrder(o, e.Sender); err != nil {
+		log.Printf("Rejecting order %x: %v", msg.ID, err)
+		return
+	}
+
+	b := c.orders
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, dup := b.orders[o.ID]; !dup {
+		b.orders[o.ID] = o
+	}
+}
+
+// checkOrder checks that the order is signed by the channel peer that sent
+// it, has not expired and trades two currencies of the channel.
+func (c *PaymentClient) checkOrder(o *Order, sender map[wallet.BackendID]wire.Address) error {
+	ch, err := c.perunClient.Channel(o.ChannelID)
+	if err != nil {
+		return errors.New("unknown channel")
+	}
+	if o.Maker != 1-ch.Idx() || !channel.EqualWireMaps(ch.Peers()[o.Maker], sender) {
+		return errors.New("sender is not the maker")
+	}
+	state := ch.State()
+	_, sellOk := assetOn(state, o.Sell)
+	_, buyOk := assetOn(state, o.Buy)
+	switch {
+	case !sellOk || !buyOk || o.Sell == o.Buy:
+		return errors.New("invalid currencies")
+	case o.Amount == nil || o.Amount.Sign() <= 0:
+		return errors.New("invalid amount")
+	case o.Price == nil || o.Price.Sign() <= 0:
+		return errors.New("invalid price")
+	case !time.Now().Before(o.Expiry):
+		return errors.New("expired")
+	}
+	data, err := o.signedData()
+	if err != nil {
+		return err
+	}
+	valid, err := wallet.VerifySignature(data, o.Sig, ch.Params().Parts[o.Maker][1])
+	if err != nil || !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// handleOrderCancel removes an order of the channel peer.
+func (c *PaymentClient) handleOrderCancel(e *wire.Envelope) {
+	msg, ok := e.Msg.(*OrderCancelMsg)
+	if !ok {
+		return
+	}
+	ch, err := c.perunClient.Channel(msg.ChannelID)
+	if err != nil || !channel.EqualWireMaps(ch.Peers()[1-ch.Idx()], e.Sender) {
+		return
+	}
+	b := c.orders
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if o, ok := b.orders[msg.ID]; ok && !o.Own && o.ChannelID == msg.ChannelID {
+		delete(b.orders, msg.ID)
+	}
+}
+
+// handleOrderFill records the announced fill of one of our orders by the
+// channel peer, so that the channel update can be matched. A fill announced
+// for a version the channel already reached is dropped, since its update was
+// rejected without it.
+func (c *PaymentClient) handleOrderFill(e *wire.Envelope) {
+	msg, ok := e.Msg.(*OrderFillMsg)
+	if !ok {
+		return
+	}
+	ch, err := c.perunClient.Channel(msg.ChannelID)
+	if err != nil || !channel.EqualWireMaps(ch.Peers()[1-ch.Idx()], e.Sender) {
+		return
+	}
+	if msg.Version <= ch.State().Version {
+		return
+	}
+	b := c.orders
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if o, ok := b.orders[msg.ID]; !ok || !o.Own || o.ChannelID != msg.ChannelID {
+		return
+	}
+	if b.fills[msg.ChannelID] == nil {
+		b.fills[msg.ChannelID] = make(map[uint64]*OrderFillMsg)
+	}
+	b.fills[msg.ChannelID][msg.Version] = msg
+}
+
+// checkOrderFill checks an incoming update that was announced to fill one of
+// our orders: the order must be open and the update must exchange the filled
+// amount for at least its price. It returns the fill, or nil if the update
+// fills no order. An update of a channel without app that takes a currency
+// we offer in an open order from us without an announced fill is rejected.
+func (c *PaymentClient) checkOrderFill(cur, next *channel.State, idx channel.Index) (*OrderFillMsg, error) {
+	b := c.orders
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fill, ok := b.fills[next.ID][next.Version]
+	// Fills announced for this or earlier versions cannot match any later
+	// update.
+	for v := range b.fills[next.ID] {
+		if v <= next.Version {
+			delete(b.fills[next.ID], v)
+		}
+	}
+	if len(b.fills[next.ID]) == 0 {
+		delete(b.fills, next.ID)
+	}
+	if !ok {
+		return nil, b.checkUnfilled(cur, next, idx)
+	}
+	o, ok := b.orders[fill.ID]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("order %x was cancelled", fill.ID)
+	case !time.Now().Before(o.Expiry):
+		return nil, fmt.Errorf("order %x expired", fill.ID)
+	case fill.Amount.Sign() <= 0 || fill.Amount.Cmp(o.Remaining()) > 0:
+		return nil, fmt.Errorf("fill of %v exceeds order %x", fill.Amount, fill.ID)
+	case fill.Payment.Cmp(ceilMul(fill.Amount, o.Price)) < 0:
+		return nil, fmt.Errorf("payment of %v below price of order %x", fill.Payment, fill.ID)
+	}
+
+	sold, _ := assetOn(next, o.Sell)
+	paid, _ := assetOn(next, o.Buy)
+	for a := range next.Assets {
+		delta := new(big.Int).Sub(next.Balances[a][idx], cur.Balances[a][idx])
+		expected := new(big.Int)
+		switch a {
+		case sold:
+			expected.Neg(fill.Amount)
+		case paid:
+			expected.Set(fill.Payment)
+		}
+		if delta.Cmp(expected) != 0 {
+			return nil, fmt.Errorf("update does not match fill of order %x", fill.ID)
+		}
+	}
+	return fill, nil
+}
+
+// checkUnfilled rejects an update without an announced fill that decreases
+// our balance of a currency we sell in an open order of the channel, so that
+// the order can only be taken at its price. The transitions of app channels
+// are validated by their app instead. It must be called with the lock held.
+func (b *orderBook) checkUnfilled(cur, next *channel.State, idx channel.Index) error {
+	if !channel.IsNoApp(next.App) {
+		return nil
+	}
+	now := time.Now()
+	for _, o := range b.orders {
+		if !o.Own || o.ChannelID != next.ID || !now.Before(o.Expiry) || o.Remaining().Sign() <= 0 {
+			continue
+		}
+		sold, ok := assetOn(next, o.Sell)
+		if ok && next.Balances[sold][idx].Cmp(cur.Balances[sold][idx]) < 0 {
+			return fmt.Errorf("update takes currency of order %x without a fill", o.ID)
+		}
+	}
+	return nil
+}
+
+// assetOn returns the index of the channel's asset on the chain.
+func assetOn(state *channel.State, b wallet.BackendID) (int, bool) {
+	for a, asset := range state.Assets {
+		if backendOf(asset) == b {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// ceilMul returns x * r rounded up.
+func ceilMul(x *big.Int, r *big.Rat) *big.Int {
+	p := new(big.Rat).Mul(new(big.Rat).SetInt(x), r)
+	q, m := new(big.Int).QuoRem(p.Num(), p.Denom(), new(big.Int))
+	if m.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
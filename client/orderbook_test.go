@@ -0,0 +1,162 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	solchannel "github.com/perun-network/perun-solana-backend/channel"
+	"perun.network/go-perun/channel"
+)
+
+func TestCeilMul(t *testing.T) {
+	tests := []struct {
+		x    int64
+		r    *big.Rat
+		want int64
+	}{
+		{10, big.NewRat(3, 1), 30},
+		{10, big.NewRat(1, 3), 4},
+		{9, big.NewRat(1, 3), 3},
+		{1, big.NewRat(1, 1000), 1},
+		{0, big.NewRat(5, 2), 0},
+	}
+	for _, tt := range tests {
+		if got := ceilMul(big.NewInt(tt.x), tt.r); got.Int64() != tt.want {
+			t.Errorf("ceilMul(%d, %v) = %v, want %d", tt.x, tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestOrderBookMatching(t *testing.T) {
+	id := channel.ID{1}
+	future := time.Now().Add(time.Hour)
+	b := newOrderBook()
+	add := func(idByte byte, o Order) {
+		o.ID = OrderID{idByte}
+		if o.ChannelID == (channel.ID{}) {
+			o.ChannelID = id
+		}
+		if o.Expiry.IsZero() {
+			o.Expiry = future
+		}
+		if o.Filled == nil {
+			o.Filled = new(big.Int)
+		}
+		b.orders[o.ID] = &o
+	}
+	// The peer sells SOL (6) for ETH (1); we sell ETH for SOL at a price of
+	// 2 SOL per ETH, so we pay at most 1/2 ETH per SOL.
+	add(1, Order{Sell: 6, Buy: 1, Amount: big.NewInt(10), Price: big.NewRat(1, 2)})
+	add(2, Order{Sell: 6, Buy: 1, Amount: big.NewInt(10), Price: big.NewRat(1, 4)})
+	add(3, Order{Sell: 6, Buy: 1, Amount: big.NewInt(10), Price: big.NewRat(1, 1)}) // Too expensive.
+	add(4, Order{Sell: 6, Buy: 1, Amount: big.NewInt(10), Price: big.NewRat(1, 4), Own: true})
+	add(5, Order{Sell: 6, Buy: 1, Amount: big.NewInt(10), Price: big.NewRat(1, 4), ChannelID: channel.ID{2}})
+	add(6, Order{Sell: 6, Buy: 1, Amount: big.NewInt(10), Price: big.NewRat(1, 4), Expiry: time.Now().Add(-time.Second)})
+	add(7, Order{Sell: 6, Buy: 1, Amount: big.NewInt(10), Price: big.NewRat(1, 4), Filled: big.NewInt(10)})
+	add(8, Order{Sell: 1, Buy: 6, Amount: big.NewInt(10), Price: big.NewRat(1, 4)})
+
+	matches := b.matching(id, 1, 6, big.NewRat(2, 1))
+	if len(matches) != 2 || matches[0].ID != (OrderID{2}) || matches[1].ID != (OrderID{1}) {
+		t.Fatalf("matching returned %v, want orders 2 and 1", matches)
+	}
+}
+
+func TestCheckOrderFill(t *testing.T) {
+	id := channel.ID{1}
+	assets := []channel.Asset{ethchannel.NewAsset(big.NewInt(1337), common.Address{}), solchannel.NewSOLSolanaCrossAsset()}
+	state := func(version uint64, eth0, eth1, sol0, sol1 int64) *channel.State {
+		return &channel.State{
+			ID:      id,
+			Version: version,
+			App:     channel.NoApp(),
+			Allocation: channel.Allocation{
+				Assets: assets,
+				Balances: channel.Balances{
+					{big.NewInt(eth0), big.NewInt(eth1)},
+					{big.NewInt(sol0), big.NewInt(sol1)},
+				},
+			},
+			Data: channel.NoData(),
+		}
+	}
+	newClient := func() *PaymentClient {
+		c := &PaymentClient{orders: newOrderBook()}
+		// We (index 0) sell 10 ETH for at least 2 SOL each.
+		c.orders.orders[OrderID{1}] = &Order{
+			ID: OrderID{1}, ChannelID: id, Sell: 1, Buy: 6, Amount: big.NewInt(10),
+			Price: big.NewRat(2, 1), Expiry: time.Now().Add(time.Hour), Own: true, Filled: new(big.Int),
+		}
+		return c
+	}
+	announce := func(c *PaymentClient, version uint64, amount, payment int64) {
+		c.orders.fills[id] = map[uint64]*OrderFillMsg{
+			version: {ID: OrderID{1}, ChannelID: id, Version: version, Amount: big.NewInt(amount), Payment: big.NewInt(payment)},
+		}
+	}
+	cur := state(4, 20, 0, 0, 20)
+
+	t.Run("fill", func(t *testing.T) {
+		c := newClient()
+		announce(c, 5, 4, 8)
+		fill, err := c.checkOrderFill(cur, state(5, 16, 4, 8, 12), 0)
+		if err != nil || fill == nil {
+			t.Fatalf("fill rejected: %v", err)
+		}
+	})
+	t.Run("fill below price", func(t *testing.T) {
+		c := newClient()
+		announce(c, 5, 4, 7)
+		if _, err := c.checkOrderFill(cur, state(5, 16, 4, 7, 13), 0); err == nil {
+			t.Fatal("fill below price accepted")
+		}
+	})
+	t.Run("update not matching fill", func(t *testing.T) {
+		c := newClient()
+		announce(c, 5, 4, 8)
+		if _, err := c.checkOrderFill(cur, state(5, 15, 5, 8, 12), 0); err == nil {
+			t.Fatal("update not matching fill accepted")
+		}
+	})
+	t.Run("take without fill", func(t *testing.T) {
+		c := newClient()
+		if _, err := c.checkOrderFill(cur, state(5, 16, 4, 8, 12), 0); err == nil {
+			t.Fatal("update taking offered currency without fill accepted")
+		}
+	})
+	t.Run("payment without fill", func(t *testing.T) {
+		c := newClient()
+		fill, err := c.checkOrderFill(cur, state(5, 20, 0, 1, 19), 0)
+		if err != nil || fill != nil {
+			t.Fatalf("payment returned fill %v, error %v", fill, err)
+		}
+	})
+	t.Run("stale fills purged", func(t *testing.T) {
+		c := newClient()
+		announce(c, 3, 4, 8)
+		c.orders.fills[id][5] = &OrderFillMsg{ID: OrderID{1}, ChannelID: id, Version: 5, Amount: big.NewInt(1), Payment: big.NewInt(2)}
+		c.orders.fills[id][7] = &OrderFillMsg{ID: OrderID{1}, ChannelID: id, Version: 7, Amount: big.NewInt(1), Payment: big.NewInt(2)}
+		if _, err := c.checkOrderFill(cur, state(5, 20, 0, 1, 19), 0); err == nil {
+			t.Fatal("update not matching fill accepted")
+		}
+		if fills := c.orders.fills[id]; len(fills) != 1 || fills[7] == nil {
+			t.Fatalf("remaining fills %v, want only version 7", fills)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// DefaultChallengeDuration is the on-chain challenge duration in seconds of
+// channels proposed without one.
+const DefaultChallengeDuration = 1000
+
+// ChannelOptions configures a channel proposal.
+type ChannelOptions struct {
+	// ChallengeDuration is the on-chain challenge duration in seconds. Zero
+	// means DefaultChallengeDuration.
+	ChallengeDuration uint64
+	// Assets are the assets of the channel. Nil means all currencies of the
+	// client.
+	Assets []channel.Asset
+	// Balances is the initial balance per asset and participant, which each
+	// participant deposits. The proposer has index 0.
+	Balances channel.Balances
+	// Nonce is our share of the channel nonce. Nil means a random share.
+	Nonce *client.NonceShare
+	// App is the channel app and Data its initial data. Nil means no app.
+	App  channel.App
+	Data channel.Data
+}
+
+// OpenChannelWith proposes a channel with the specified peer and options and
+// waits until it is funded. If the funding fails, our deposits are reclaimed
+// and a FundingFailure is returned.
+func (c *PaymentClient) OpenChannelWith(ctx context.Context, peer map[wallet.BackendID]wire.Address, opts ChannelOptions) (*PaymentChannel, error) {
+	proposal, err := c.newProposal(peer, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Sending channel proposal", proposal)
+	ch, err := c.perunClient.ProposeChannel(ctx, proposal)
+	var fundingErr *client.ChannelFundingError
+	if errors.As(err, &fundingErr) && ch != nil {
+		// Reclaim our deposits before giving up on the channel.
+		return nil, c.recoverFunding(ch, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("proposing channel: %w", err)
+	}
+
+	log.Println("Starting dispute watcher", ch.ID())
+	return c.openedChannel(ch), nil
+}
+
+// newProposal creates a ledger channel proposal to the peer. The proposer has
+// always index 0. Here we use the on-chain addresses as off-chain addresses,
+// but we could also use different ones.
+func (c *PaymentClient) newProposal(peer map[wallet.BackendID]wire.Address, opts ChannelOptions) (*client.LedgerChannelProposalMsg, error) {
+	participants := []map[wallet.BackendID]wire.Address{c.waddress, peer}
+	assets := opts.Assets
+	if assets == nil {
+		assets = c.currency
+	}
+	if len(opts.Balances) != len(assets) {
+		return nil, fmt.Errorf("expected balances of %d assets, got %d", len(assets), len(opts.Balances))
+	}
+	backends := make([]wallet.BackendID, len(assets))
+	for a, asset := range assets {
+		if !c.isCurrency(asset) {
+			return nil, fmt.Errorf("unknown asset %v", asset)
+		}
+		if len(opts.Balances[a]) != len(participants) {
+			return nil, fmt.Errorf("expected %d balances of asset %d, got %d", len(participants), a, len(opts.Balances[a]))
+		}
+		backends[a] = backendOf(asset)
+	}
+
+	// We create an initial allocation which defines the starting balances.
+	initAlloc := channel.NewAllocation(len(participants), backends, assets...)
+	initAlloc.Balances = opts.Balances.Clone()
+
+	log.Println("Creating channel proposal")
+	proposal, err := client.NewLedgerChannelProposal(
+		opts.challengeDuration(),
+		c.account,
+		initAlloc,
+		participants,
+		opts.proposalOpts()...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating proposal: %w", err)
+	}
+	return proposal, nil
+}
+
+// challengeDuration returns the challenge duration of the options.
+func (opts ChannelOptions) challengeDuration() uint64 {
+	if opts.ChallengeDuration == 0 {
+		return DefaultChallengeDuration
+	}
+	return opts.ChallengeDuration
+}
+
+// proposalOpts returns the nonce and app options of a proposal.
+func (opts ChannelOptions) proposalOpts() []client.ProposalOpts {
+	nonce := client.WithRandomNonce()
+	if opts.Nonce != nil {
+		nonce = client.WithNonce(*opts.Nonce)
+	}
+	app := client.WithoutApp()
+	if opts.App != nil {
+		app = client.WithApp(opts.App, opts.Data)
+	}
+	return []client.ProposalOpts{nonce, app}
+}
+
+// isCurrency returns whether the asset is one of the client's currencies.
+func (c *PaymentClient) isCurrency(asset channel.Asset) bool {
+	return containsAsset(c.currency, asset)
+}
+
+// containsAsset returns whether the asset is one of the given assets.
+func containsAsset(assets []channel.Asset, asset channel.Asset) bool {
+	for _, a := range assets {
+		if a.Equal(asset) {
+			return true
+		}
+	}
+	return false
+}
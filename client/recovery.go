@@ -0,0 +1,231 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+)
+
+const (
+	// recoveryMargin is the time for registering and withdrawing on top of
+	// the challenge duration.
+	recoveryMargin = 5 * time.Minute
+	// signedStateTimeout bounds waiting for the watcher to receive the
+	// signed initial state.
+	signedStateTimeout = 10 * time.Second
+)
+
+// errSolanaRegister is reported for deposits on Solana, because the Solana
+// backend does not implement registering states yet.
+var errSolanaRegister = errors.New("the Solana backend cannot register states yet")
+
+// RecoveryStage is the progress of reclaiming our deposit on a chain after
+// the funding of a channel failed.
+type RecoveryStage int
+
+// Recovery stages.
+const (
+	RecoveryNotNeeded  RecoveryStage = iota // We have no deposit on the chain.
+	RecoveryRegistered                      // The initial state is registered.
+	RecoveryReclaimed                       // Our deposit was withdrawn.
+	RecoveryFailed                          // Our deposit could not be reclaimed.
+)
+
+// String returns the name of the recovery stage.
+func (s RecoveryStage) String() string {
+	switch s {
+	case RecoveryNotNeeded:
+		return "not needed"
+	case RecoveryRegistered:
+		return "registered"
+	case RecoveryReclaimed:
+		return "reclaimed"
+	case RecoveryFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// ChainRecovery is the outcome of reclaiming our deposit on a chain.
+type ChainRecovery struct {
+	Backend wallet.BackendID
+	Stage   RecoveryStage
+	Err     error // Why the deposit could not be reclaimed.
+}
+
+// FundingFailure is returned when a channel could not be funded. It holds
+// the outcome of reclaiming our deposits on each chain.
+type FundingFailure struct {
+	ChannelID channel.ID
+	Err       error
+	Chains    []ChainRecovery
+}
+
+// Error implements error.
+func (e *FundingFailure) Error() string {
+	chains := make([]string, len(e.Chains))
+	for i, r := range e.Chains {
+		chains[i] = chainName(r.Backend) + " " + r.Stage.String()
+		if r.Err != nil {
+			chains[i] += fmt.Sprintf(" (%v)", r.Err)
+		}
+	}
+	return fmt.Sprintf("funding channel %x failed: %v; recovery: %s", e.ChannelID, e.Err, strings.Join(chains, ", "))
+}
+
+// Unwrap returns the funding error.
+func (e *FundingFailure) Unwrap() error {
+	return e.Err
+}
+
+// newFundingFailure creates the failure of the channel's funding. The
+// ChannelFundingError of go-perun does not unwrap, so its cause is stored
+// instead to keep our FundingError reachable with errors.As.
+func newFundingFailure(id channel.ID, fundingErr error) *FundingFailure {
+	var cfe *client.ChannelFundingError
+	if errors.As(fundingErr, &cfe) {
+		fundingErr = cfe.Err
+	}
+	return &FundingFailure{ChannelID: id, Err: fundingErr}
+}
+
+// recoverFunding reclaims our deposits of a channel whose funding failed. On
+// each chain where we deposited, it registers the signed initial state and
+// withdraws our deposit after the challenge period. The channel is closed
+// afterwards.
+func (c *PaymentClient) recoverFunding(ch *client.Channel, fundingErr error) *FundingFailure {
+	failure := newFundingFailure(ch.ID(), fundingErr)
+	defer ch.Close()
+
+	timeout := time.Duration(ch.Params().ChallengeDuration)*time.Second + recoveryMargin
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// The watcher receives the signed initial state, which also protects it
+	// against registering an outdated state.
+	c.startWatching(ch)
+	state, err := c.awaitSignedState(ctx, ch.ID())
+
+	for _, backend := range []wallet.BackendID{1, 6} {
+		r := ChainRecovery{Backend: backend}
+		deposited, derr := c.deposited(ctx, ch, backend)
+		switch {
+		case derr != nil:
+			r.Stage, r.Err = RecoveryFailed, derr
+		case !deposited:
+			r.Stage = RecoveryNotNeeded
+		case err != nil:
+			r.Stage, r.Err = RecoveryFailed, err
+		default:
+			r = c.reclaim(ctx, ch, state, backend)
+		}
+		log.Printf("Recovering %s deposit of channel %x: %v", chainName(backend), ch.ID(), r.Stage)
+		failure.Chains = append(failure.Chains, r)
+	}
+	return failure
+}
+
+// awaitSignedState waits until the watcher received the signed state of the
+// channel.
+func (c *PaymentClient) awaitSignedState(ctx context.Context, id channel.ID) (channel.SignedState, error) {
+	ctx, cancel := context.WithTimeout(ctx, signedStateTimeout)
+	defer cancel()
+	for {
+		if state, ok := c.watcher.latestState(id); ok {
+			return state, nil
+		}
+		select {
+		case <-ctx.Done():
+			return channel.SignedState{}, errors.New("signed initial state not available")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// deposited returns whether we deposited any asset of the channel on the
+// chain.
+func (c *PaymentClient) deposited(ctx context.Context, ch *client.Channel, backend wallet.BackendID) (bool, error) {
+	state := ch.State()
+	for a, asset := range state.Assets {
+		if backendOf(asset) != backend || state.Balances[a][ch.Idx()].Sign() == 0 {
+			continue
+		}
+		deposits, err := c.depositCheckers[backend].deposits(ctx, ch.Params(), state, a)
+		if err != nil {
+			return false, errors.WithMessage(err, "checking deposits")
+		}
+		if deposits[ch.Idx()].Status != DepositMissing {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reclaim registers the signed state on a single chain and withdraws our
+// deposit once the challenge period is over.
+func (c *PaymentClient) reclaim(ctx context.Context, ch *client.Channel, state channel.SignedState, backend wallet.BackendID) ChainRecovery {
+	r := ChainRecovery{Backend: backend, Stage: RecoveryFailed}
+	if backend == 6 {
+		r.Err = errSolanaRegister
+		return r
+	}
+
+	acc := make(map[wallet.BackendID]wallet.Account)
+	for id, w := range c.wallets {
+		a, err := w.Unlock(c.account[id])
+		if err != nil {
+			r.Err = errors.WithMessage(err, "unlocking account")
+			return r
+		}
+		acc[id] = a
+	}
+	req := channel.AdjudicatorReq{
+		Params: state.Params,
+		Acc:    acc,
+		Tx:     channel.Transaction{State: state.State, Sigs: state.Sigs},
+		Idx:    ch.Idx(),
+	}
+	adj := c.adjudicators[backend]
+	if err := adj.Register(ctx, req, nil); err != nil {
+		r.Err = errors.WithMessage(err, "registering initial state")
+		return r
+	}
+	r.Stage = RecoveryRegistered
+
+	// Withdrawing concludes the channel after the challenge period.
+	if err := adj.Withdraw(ctx, req, nil); err != nil {
+		r.Err = errors.WithMessage(err, "withdrawing deposit")
+		return r
+	}
+	r.Stage = RecoveryReclaimed
+	c.events.publish(Event{
+		Type:      Withdrawn,
+		ChannelID: ch.ID(),
+		Version:   state.State.Version,
+		Backend:   backend,
+		New:       allocationOf(state.State),
+	})
+	return r
+}
@@ -0,0 +1,40 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+)
+
+func TestFundingFailureUnwrapsFundingError(t *testing.T) {
+	ferr := &FundingError{Backend: 1, Cause: FundingPeerTimeout, Peers: []channel.Index{1}, Err: errors.New("timeout")}
+	// go-perun wraps the funder's error like this.
+	perunErr := &client.ChannelFundingError{Err: pkgerrors.WithMessage(ferr, "waiting for peer funding")}
+
+	failure := newFundingFailure(channel.ID{1}, perunErr)
+
+	var got *FundingError
+	if !errors.As(failure, &got) {
+		t.Fatalf("errors.As does not reach the FundingError of %v", failure)
+	}
+	if got != ferr {
+		t.Errorf("got FundingError %v, want %v", got, ferr)
+	}
+}
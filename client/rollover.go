@@ -0,0 +1,158 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// Rollover finalizes the channel and proposes a successor channel with the
+// same peer, in which the final balances are carried over and adjusted by
+// delta. delta[a][i] is added to the balance of participant i for asset a:
+// positive amounts are deposited (top-up), negative amounts are withdrawn. A
+// nil delta carries over the balances unchanged.
+//
+// The proposal marks the successor as a rollover of this channel with a nonce
+// share derived from its ID. The peer accepts it if it does not have to
+// deposit more than its carried-over balance, plus a top-up within its
+// funding limits. The successor is funded from the participants' accounts
+// before the channel is settled, so both need the liquidity of their new
+// balances meanwhile. If the successor is rejected, the channel stays open in
+// its final state and can be settled with Settle.
+//
+// Only channels that can be settled can be rolled over, which currently means
+// channels holding ETH only. For channels with assets on Solana, Rollover
+// returns ErrSettlementUnsupported before the channel is finalized.
+func (c *PaymentChannel) Rollover(ctx context.Context, delta channel.Balances) (*PaymentChannel, error) {
+	parts := len(c.ch.Params().Parts)
+	if parts != 2 {
+		return nil, fmt.Errorf("rollover of channel with %d participants", parts)
+	}
+	assets := c.ch.State().Assets
+	if err := checkSettleable(assets); err != nil {
+		return nil, fmt.Errorf("channel cannot be rolled over: %w", err)
+	}
+	if delta == nil {
+		delta = channel.MakeBalances(len(assets), parts)
+	}
+	if len(delta) != len(assets) {
+		return nil, fmt.Errorf("expected balance adjustments for %d assets, got %d", len(assets), len(delta))
+	}
+	for a := range delta {
+		if len(delta[a]) != parts {
+			return nil, fmt.Errorf("expected balance adjustments for %d participants of asset %d, got %d", parts, a, len(delta[a]))
+		}
+	}
+
+	// Finalize the channel so that its balances cannot change anymore.
+	if err := c.finalize(); err != nil {
+		return nil, fmt.Errorf("finalizing channel: %w", err)
+	}
+
+	// The proposer has always index 0 in the successor.
+	me := c.ch.Idx()
+	peer := 1 - me
+	final := c.ch.State()
+	backends := make([]wallet.BackendID, len(assets))
+	for a, asset := range assets {
+		backends[a] = backendOf(asset)
+	}
+	alloc := channel.NewAllocation(2, backends, assets...)
+	for a := range assets {
+		for newIdx, oldIdx := range []channel.Index{me, peer} {
+			bal := new(big.Int).Add(final.Balances[a][oldIdx], delta[a][oldIdx])
+			if bal.Sign() < 0 {
+				return nil, fmt.Errorf("negative balance of participant %d for asset %d: %v", oldIdx, a, bal)
+			}
+			alloc.Balances[a][newIdx] = bal
+		}
+	}
+
+	proposal, err := client.NewLedgerChannelProposal(
+		c.ch.Params().ChallengeDuration,
+		c.client.account,
+		alloc,
+		[]map[wallet.BackendID]wire.Address{c.client.waddress, c.ch.Peers()[peer]},
+		client.WithNonce(rolloverNonce(c.ch.ID())),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating successor proposal: %w", err)
+	}
+	ch, err := c.client.perunClient.ProposeChannel(ctx, proposal)
+	if err != nil {
+		return nil, fmt.Errorf("proposing successor channel: %w", err)
+	}
+	successor := c.client.openedChannel(ch)
+
+	// Withdraw the final balances now that the successor is funded.
+	if err := c.client.settle(ctx, c.ch, false); err != nil {
+		return successor, fmt.Errorf("settling channel: %w", err)
+	}
+	return successor, nil
+}
+
+// rolloverNonce returns the proposer's nonce share of the successor of the
+// channel with the given ID. The channel nonce still depends on the random
+// share of the acceptor.
+func rolloverNonce(pred channel.ID) client.NonceShare {
+	return sha256.Sum256(append([]byte("rollover"), pred[:]...))
+}
+
+// predecessor returns the final, unsettled channel with the proposer of the
+// ledger channel proposal, if the proposal is marked as its rollover.
+func (c *PaymentClient) predecessor(lcp *client.LedgerChannelProposalMsg) (*client.Channel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.openChannels {
+		if lcp.NonceShare != rolloverNonce(ch.ID()) || !ch.State().IsFinal {
+			continue
+		}
+		for i, p := range ch.Peers() {
+			if channel.Index(i) != ch.Idx() && channel.EqualWireMaps(p, lcp.Peers[0]) {
+				return ch, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// checkRollover checks that a successor proposal requires us to deposit at
+// most the balances carried over from the predecessor, plus a top-up within
+// the funding limits.
+func checkRollover(pred *client.Channel, lcp *client.LedgerChannelProposalMsg, idx channel.Index, limits map[wallet.BackendID]channel.Bal) error {
+	final := pred.State()
+	if err := channel.AssertAssetsEqual(final.Assets, lcp.InitBals.Assets); err != nil {
+		return fmt.Errorf("assets differ from predecessor: %v", err)
+	}
+	if err := checkSettleable(final.Assets); err != nil {
+		return err
+	}
+	for a, asset := range final.Assets {
+		topUp := new(big.Int).Sub(lcp.FundingAgreement[a][idx], final.Balances[a][pred.Idx()])
+		limit, ok := limits[backendOf(asset)]
+		if ok && topUp.Cmp(limit) > 0 {
+			return fmt.Errorf("funding of asset %d exceeds carried-over balance by %v", a, topUp)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+)
+
+const defaultShutdownDeadline = 5 * time.Minute
+
+// ShutdownMode determines what happens to the open channels on a graceful
+// shutdown.
+type ShutdownMode int
+
+// Shutdown modes.
+const (
+	ShutdownSettle   ShutdownMode = iota // Cooperatively settle all channels.
+	ShutdownPersist                      // Leave the channels in the database for Restore.
+	ShutdownDelegate                     // Hand the channels over to a watchtower.
+)
+
+// ShutdownConfig configures a graceful shutdown.
+type ShutdownConfig struct {
+	Mode       ShutdownMode
+	Deadline   time.Duration // Time to handle all channels, 5 minutes if 0.
+	Watchtower Watchtower    // Watchtower used by ShutdownDelegate.
+}
+
+// ChannelOutcome is the state in which a channel was left on shutdown.
+type ChannelOutcome int
+
+// Channel outcomes.
+const (
+	ChannelAbandoned ChannelOutcome = iota // Left open and unwatched.
+	ChannelSettled                         // Settled and funds withdrawn.
+	ChannelPersisted                       // Stored for Restore.
+	ChannelDelegated                       // Watched by the watchtower.
+)
+
+// String returns the name of the channel outcome.
+func (o ChannelOutcome) String() string {
+	switch o {
+	case ChannelAbandoned:
+		return "abandoned"
+	case ChannelSettled:
+		return "settled"
+	case ChannelPersisted:
+		return "persisted"
+	case ChannelDelegated:
+		return "delegated"
+	default:
+		return "unknown"
+	}
+}
+
+// ChannelShutdown reports what happened to a channel on shutdown.
+type ChannelShutdown struct {
+	ChannelID channel.ID
+	Version   uint64
+	Outcome   ChannelOutcome
+	Err       error // Why the channel was abandoned.
+}
+
+// ShutdownReport reports the outcome of a graceful shutdown.
+type ShutdownReport struct {
+	Channels []ChannelShutdown
+}
+
+// String returns a line per channel.
+func (r ShutdownReport) String() string {
+	s := fmt.Sprintf("%d channel(s)", len(r.Channels))
+	for _, ch := range r.Channels {
+		s += fmt.Sprintf("\n%x version %d: %v", ch.ChannelID, ch.Version, ch.Outcome)
+		if ch.Err != nil {
+			s += fmt.Sprintf(" (%v)", ch.Err)
+		}
+	}
+	return s
+}
+
+// GracefulShutdown handles all open channels according to the configuration
+// within its deadline, shuts down the client and reports the outcome of each
+// channel.
+func (c *PaymentClient) GracefulShutdown(cfg ShutdownConfig) ShutdownReport {
+	if cfg.Deadline == 0 {
+		cfg.Deadline = defaultShutdownDeadline
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Deadline)
+	defer cancel()
+
+	c.mu.Lock()
+	open := make([]*client.Channel, 0, len(c.openChannels))
+	for _, ch := range c.openChannels {
+		open = append(open, ch)
+	}
+	c.mu.Unlock()
+
+	report := ShutdownReport{Channels: make([]ChannelShutdown, len(open))}
+	var wg sync.WaitGroup
+	for i, ch := range open {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome, err := c.shutdownChannel(ctx, ch, cfg)
+			if err != nil {
+				outcome = ChannelAbandoned
+			}
+			report.Channels[i] = ChannelShutdown{
+				ChannelID: ch.ID(),
+				Version:   ch.State().Version,
+				Outcome:   outcome,
+				Err:       err,
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Shutdown()
+	return report
+}
+
+// shutdownChannel handles a single channel on shutdown.
+func (c *PaymentClient) shutdownChannel(ctx context.Context, ch *client.Channel, cfg ShutdownConfig) (ChannelOutcome, error) {
+	switch cfg.Mode {
+	case ShutdownSettle:
+		if err := checkSettleable(ch.State().Assets); err != nil {
+			return ChannelAbandoned, err
+		}
+		if !ch.State().IsFinal {
+			err := ch.Update(ctx, func(state *channel.State) {
+				state.IsFinal = true
+			})
+			if err != nil {
+				// Without the peer, the channel can only be settled after
+				// the challenge duration.
+				log.Printf("Finalizing channel %x failed, settling non-cooperatively: %v", ch.ID(), err)
+			}
+		}
+		if err := c.settle(ctx, ch, false); err != nil {
+			return ChannelAbandoned, fmt.Errorf("settling: %w", err)
+		}
+		return ChannelSettled, nil
+
+	case ShutdownPersist:
+		if c.persister == nil {
+			return ChannelAbandoned, errors.New("persistence not enabled")
+		}
+		return ChannelPersisted, nil
+
+	case ShutdownDelegate:
+		if cfg.Watchtower == nil {
+			return ChannelAbandoned, errors.New("no watchtower configured")
+		}
+		state, ok := c.watcher.latestState(ch.ID())
+		if !ok {
+			return ChannelAbandoned, errors.New("no signed state")
+		}
+		if err := cfg.Watchtower.Watch(ctx, state); err != nil {
+			return ChannelAbandoned, fmt.Errorf("delegating to watchtower: %w", err)
+		}
+		return ChannelDelegated, nil
+
+	default:
+		return ChannelAbandoned, fmt.Errorf("unknown shutdown mode: %d", cfg.Mode)
+	}
+}
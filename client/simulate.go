@@ -0,0 +1,392 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	ethwallet "github.com/perun-network/perun-eth-backend/wallet"
+	ethwire "github.com/perun-network/perun-eth-backend/wire"
+	solwallet "github.com/perun-network/perun-solana-backend/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+	swire "perun.network/go-perun/wire/net/simple"
+	"perun.network/sol-eth-cross-chain-demo/oracle"
+)
+
+// DefaultSolanaTxFee is the fee in lamports that simulations estimate per
+// Solana transaction: the base fee of one signature, without priority fee.
+const DefaultSolanaTxFee = 5000
+
+// SolanaOpen is the Solana transaction that creates the channel account
+// before the deposits. It has no configurable gas limit.
+const SolanaOpen GasOperation = "open"
+
+// gasPriceSuggester suggests the gas price of the next transaction.
+type gasPriceSuggester interface {
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+}
+
+// NewSimulationClient creates a client that only simulates operations. It
+// connects to no node, so the contract addresses may be placeholders. Only
+// the Simulate methods, Policy and WireAddress may be called on it. Without a
+// gas strategy in the options, Ethereum fees are unknown.
+func NewSimulationClient(
+	ethAddress *ethwallet.Address,
+	chainID uint64,
+	assetAddr ethwallet.Address,
+	solAccount *solwallet.Account,
+	solAsset channel.Asset,
+	opts ...Option,
+) (*PaymentClient, error) {
+	o := makeOptions(opts)
+	solPart, ok := solAccount.Address().(*solwallet.Participant)
+	if !ok {
+		return nil, errors.New("solana account address is not a participant")
+	}
+	ethAsset := ethchannel.NewAsset(new(big.Int).SetUint64(chainID), common.Address(assetAddr))
+	return &PaymentClient{
+		account:       map[wallet.BackendID]wallet.Address{1: ethAddress, 6: solPart},
+		waddress:      map[wallet.BackendID]wire.Address{1: &ethwire.Address{Address: ethAddress}, 6: swire.NewAddress(solPart.String())},
+		currency:      []channel.Asset{ethAsset, solAsset},
+		fundingLimits: o.fundingLimits,
+		oracle:        o.oracle,
+		slippage:      new(big.Rat).SetFloat64(o.slippage),
+		gas:           o.gas,
+		solanaTxFee:   o.solanaTxFee,
+	}, nil
+}
+
+// PeerPolicy is the known policy with which a peer handles our proposals and
+// updates. Simulations check operations against it.
+type PeerPolicy struct {
+	Currencies    []channel.Asset                  // Accepted assets, nil for ours.
+	FundingLimits map[wallet.BackendID]channel.Bal // Maximum deposit of the peer per chain.
+	Oracle        oracle.Oracle                    // Prices the peer values swaps with, nil if it accepts no swaps.
+	Slippage      *big.Rat                         // Tolerated shortfall of a swap's value, nil for none.
+}
+
+// Policy returns the policy with which the client handles proposals and
+// updates, which is the policy of a peer running with the same options.
+func (c *PaymentClient) Policy() PeerPolicy {
+	return PeerPolicy{
+		Currencies:    c.currency,
+		FundingLimits: c.fundingLimits,
+		Oracle:        c.oracle,
+		Slippage:      c.slippage,
+	}
+}
+
+// currencies returns the accepted assets, or own if the policy has none.
+func (p PeerPolicy) currencies(own []channel.Asset) []channel.Asset {
+	if p.Currencies == nil {
+		return own
+	}
+	return p.Currencies
+}
+
+func (p PeerPolicy) slippage() *big.Rat {
+	if p.Slippage == nil {
+		return new(big.Rat)
+	}
+	return p.Slippage
+}
+
+// SimulatedTx is an on-chain transaction that an operation would send.
+type SimulatedTx struct {
+	Backend   wallet.BackendID // Chain of the transaction.
+	Operation GasOperation
+	Sender    channel.Index // Participant sending the transaction.
+	Amount    *big.Int      // Deposited or withdrawn amount, nil for other operations.
+	Gas       uint64        // Gas limit on Ethereum, 0 on Solana.
+	Fee       *big.Int      // Estimated fee in wei or lamports, nil if unknown.
+}
+
+// Simulation is the outcome of an operation computed without sending
+// anything.
+type Simulation struct {
+	Operation  string           // Simulated operation.
+	Before     channel.Balances // Balances before the operation, nil when opening.
+	State      *channel.State   // Channel state after the operation.
+	Txs        []SimulatedTx    // On-chain transactions in the order they are sent.
+	Violations []string         // Reasons for which the peer would reject the operation or it would fail.
+	Warnings   []string         // Caveats of the simulated outcome.
+}
+
+// Accepted returns whether the peer would accept the operation and it would
+// succeed.
+func (s *Simulation) Accepted() bool {
+	return len(s.Violations) == 0
+}
+
+// Fees returns the estimated fees per chain of the transactions sent by the
+// given participant. Transactions with unknown fees are not included.
+func (s *Simulation) Fees(idx channel.Index) map[wallet.BackendID]*big.Int {
+	fees := make(map[wallet.BackendID]*big.Int)
+	for _, tx := range s.Txs {
+		if tx.Sender != idx || tx.Fee == nil {
+			continue
+		}
+		if fees[tx.Backend] == nil {
+			fees[tx.Backend] = new(big.Int)
+		}
+		fees[tx.Backend].Add(fees[tx.Backend], tx.Fee)
+	}
+	return fees
+}
+
+// String returns a human-readable report of the simulation.
+func (s *Simulation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Simulated %s:\n", s.Operation)
+	if s.Before != nil {
+		fmt.Fprintf(&b, "  balances: %v -> %v\n", s.Before, s.State.Balances)
+	} else {
+		fmt.Fprintf(&b, "  balances: %v\n", s.State.Balances)
+	}
+	if len(s.Txs) == 0 {
+		b.WriteString("  no on-chain transactions\n")
+	}
+	for _, tx := range s.Txs {
+		unit := currencyUnits[tx.Backend]
+		fmt.Fprintf(&b, "  %s %s by participant %d", unit.symbol, tx.Operation, tx.Sender)
+		if tx.Amount != nil {
+			fmt.Fprintf(&b, ", amount %v %s", tx.Amount, unit.base)
+		}
+		if tx.Gas > 0 {
+			fmt.Fprintf(&b, ", gas limit %d", tx.Gas)
+		}
+		if tx.Fee != nil {
+			fmt.Fprintf(&b, ", fee %v %s\n", tx.Fee, unit.base)
+		} else {
+			b.WriteString(", fee unknown\n")
+		}
+	}
+	for _, v := range s.Violations {
+		fmt.Fprintf(&b, "  rejected: %s\n", v)
+	}
+	for _, w := range s.Warnings {
+		fmt.Fprintf(&b, "  note: %s\n", w)
+	}
+	return b.String()
+}
+
+// SimulateOpenChannel simulates OpenChannel.
+func (c *PaymentClient) SimulateOpenChannel(ctx context.Context, peer map[wallet.BackendID]wire.Address, ethAmount float64, solAmount uint64, policy PeerPolicy) (*Simulation, error) {
+	return c.SimulateOpenChannelWith(ctx, peer, swapChannelOptions(ethAmount, solAmount), policy)
+}
+
+// SimulateOpenChannelWith simulates OpenChannelWith: it creates the proposal,
+// checks it against the peer's policy and lists the deposits on each chain.
+// The ID of the resulting state is unknown, since it depends on the peer's
+// nonce share.
+func (c *PaymentClient) SimulateOpenChannelWith(ctx context.Context, peer map[wallet.BackendID]wire.Address, opts ChannelOptions, policy PeerPolicy) (*Simulation, error) {
+	proposal, err := c.newProposal(peer, opts)
+	if err != nil {
+		return nil, err
+	}
+	state := &channel.State{
+		Allocation: proposal.InitBals.Clone(),
+		App:        proposal.App,
+		Data:       proposal.InitData,
+	}
+	sim := &Simulation{Operation: "open", State: state}
+
+	const peerIdx = 1
+	if err := checkProposal(proposal.Base(), policy.currencies(c.currency)); err != nil {
+		sim.Violations = append(sim.Violations, err.Error())
+	}
+	if err := checkFundingLimits(proposal.InitBals.Assets, proposal.FundingAgreement, peerIdx, policy.FundingLimits); err != nil {
+		sim.Violations = append(sim.Violations, err.Error())
+	}
+
+	price := c.simulatedGasPrice(ctx, sim)
+	opened := false
+	for a, asset := range state.Assets {
+		backend := backendOf(asset)
+		if backend == 6 && !opened {
+			// The proposer creates the channel account on Solana.
+			sim.Txs = append(sim.Txs, c.simulatedTx(backend, SolanaOpen, 0, nil, price))
+			opened = true
+		}
+		for p, bal := range state.Balances[a] {
+			if bal.Sign() > 0 {
+				sim.Txs = append(sim.Txs, c.simulatedTx(backend, GasDeposit, channel.Index(p), bal, price))
+			}
+		}
+	}
+	return sim, nil
+}
+
+// SimulateSwap simulates PerformSwap on the given state of a channel in which
+// we have index idx, and checks the update against the peer's policy.
+func (c *PaymentClient) SimulateSwap(state *channel.State, idx channel.Index, policy PeerPolicy) (*Simulation, error) {
+	if state.NumParts() != 2 || len(state.Assets) != 2 {
+		return nil, errors.New("swaps need two participants and two assets")
+	}
+	next := state.Clone()
+	next.Version++
+	next.Balances = channel.Balances{
+		{state.Balances[0][1], state.Balances[0][0]},
+		{state.Balances[1][1], state.Balances[1][0]},
+	}.Clone()
+	next.IsFinal = true
+	sim := &Simulation{Operation: "swap", Before: state.Balances.Clone(), State: next}
+
+	// The peer accepts the swap if it is worth its price or, like any other
+	// update, does not decrease its balances.
+	swap, err := checkSwap(policy.Oracle, policy.slippage(), state, client.ChannelUpdate{State: next, ActorIdx: idx})
+	switch {
+	case err != nil:
+		sim.Violations = append(sim.Violations, err.Error())
+	case !channel.IsNoApp(next.App):
+		sim.Warnings = append(sim.Warnings, "the app of the channel validates the swap")
+	case !swap:
+		if err := checkNoDecrease(state, next, idx); err != nil {
+			sim.Violations = append(sim.Violations, err.Error())
+		}
+	}
+	return sim, nil
+}
+
+// SimulateSettle simulates settling the given state of a channel in which we
+// have index idx. If cooperative is set, a non-final state is finalized off
+// chain first, as Settle does. Otherwise, we register it on each chain and
+// conclude after the challenge duration. Like Settle, it reports a violation
+// if the channel has assets on a chain on which it cannot be settled.
+func (c *PaymentClient) SimulateSettle(ctx context.Context, state *channel.State, idx channel.Index, cooperative bool) (*Simulation, error) {
+	next := state.Clone()
+	register := !state.IsFinal && !cooperative
+	if !state.IsFinal && cooperative {
+		next.Version++
+		next.IsFinal = true
+	}
+	sim := &Simulation{Operation: "settle", Before: state.Balances.Clone(), State: next}
+
+	price := c.simulatedGasPrice(ctx, sim)
+	var backends []wallet.BackendID
+	for _, asset := range next.Assets {
+		if b := backendOf(asset); !containsBackend(backends, b) {
+			backends = append(backends, b)
+		}
+	}
+	for _, backend := range backends {
+		if register {
+			sim.Txs = append(sim.Txs, c.simulatedTx(backend, GasRegister, idx, nil, price))
+		}
+		sim.Txs = append(sim.Txs, c.simulatedTx(backend, GasConclude, idx, nil, price))
+		for a, asset := range next.Assets {
+			if backendOf(asset) != backend {
+				continue
+			}
+			for p, bal := range next.Balances[a] {
+				if bal.Sign() > 0 {
+					sim.Txs = append(sim.Txs, c.simulatedTx(backend, GasWithdraw, channel.Index(p), bal, price))
+				}
+			}
+		}
+	}
+
+	if register {
+		sim.Warnings = append(sim.Warnings, "the conclude waits for the challenge duration after the register")
+	}
+	if err := checkSettleable(next.Assets); err != nil {
+		sim.Violations = append(sim.Violations, err.Error())
+	}
+	return sim, nil
+}
+
+// SimulateSwap simulates PerformSwap on the current state of the channel.
+func (c PaymentChannel) SimulateSwap(policy PeerPolicy) (*Simulation, error) {
+	return c.client.SimulateSwap(c.ch.State().Clone(), c.ch.Idx(), policy)
+}
+
+// SimulateSettle simulates Settle on the current state of the channel.
+func (c PaymentChannel) SimulateSettle(ctx context.Context) (*Simulation, error) {
+	return c.client.SimulateSettle(ctx, c.ch.State().Clone(), c.ch.Idx(), true)
+}
+
+// simulatedTx creates a simulated transaction with its estimated fee. The
+// fee of an Ethereum transaction is its gas limit at the given price, an
+// upper bound of the actual fee.
+func (c *PaymentClient) simulatedTx(backend wallet.BackendID, op GasOperation, sender channel.Index, amount *big.Int, gasPrice *big.Int) SimulatedTx {
+	tx := SimulatedTx{Backend: backend, Operation: op, Sender: sender}
+	if amount != nil {
+		tx.Amount = new(big.Int).Set(amount)
+	}
+	switch backend {
+	case 1:
+		tx.Gas = c.gasLimit(op)
+		if gasPrice != nil {
+			tx.Fee = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(tx.Gas))
+		}
+	case 6:
+		tx.Fee = new(big.Int).SetUint64(c.solanaTxFee)
+	}
+	return tx
+}
+
+// gasLimit returns the gas limit of our Ethereum transactions of the given
+// operation, using the same fallbacks as the depositor and adjudicator.
+func (c *PaymentClient) gasLimit(op GasOperation) uint64 {
+	if op == GasDeposit {
+		return c.gas.limit(op, 50000)
+	}
+	return c.gas.limit(op, c.gas.limit(GasRegister, 1000000))
+}
+
+// simulatedGasPrice returns the price per gas of our next Ethereum
+// transaction: the gas price or fee cap of the fee strategy, or the price
+// suggested by the node. If it cannot be determined, a warning is added to
+// the simulation and nil is returned.
+func (c *PaymentClient) simulatedGasPrice(ctx context.Context, sim *Simulation) *big.Int {
+	if c.gas.Strategy != nil {
+		fees, err := c.gas.Strategy.Fees(ctx)
+		if err != nil {
+			sim.Warnings = append(sim.Warnings, fmt.Sprintf("determining gas fees: %v", err))
+			return nil
+		}
+		if fees.GasPrice != nil {
+			return fees.GasPrice
+		}
+		return fees.GasFeeCap
+	}
+	if c.gasPrices == nil {
+		return nil
+	}
+	price, err := c.gasPrices.SuggestGasPrice(ctx)
+	if err != nil {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("suggesting gas price: %v", err))
+		return nil
+	}
+	return price
+}
+
+func containsBackend(backends []wallet.BackendID, b wallet.BackendID) bool {
+	for _, x := range backends {
+		if x == b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,299 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+)
+
+const (
+	defaultStreamInterval = time.Second
+	streamUpdateTimeout   = 30 * time.Second
+)
+
+// StreamConfig configures a payment stream.
+type StreamConfig struct {
+	Asset    channel.Asset // Streamed asset. Nil means SOL.
+	Rate     channel.Bal   // Amount paid per second.
+	Interval time.Duration // Time between updates. Zero means one second.
+	Budget   channel.Bal   // Total amount after which the stream stops.
+}
+
+// Stream pays the channel peer continuously at a fixed rate. Each update pays
+// the amount accrued since the previous one, so if an update takes longer
+// than the interval, the next one pays the backlog in a single batch.
+type Stream struct {
+	ch  *PaymentChannel
+	cfg StreamConfig
+
+	mu        sync.Mutex
+	active    time.Duration // Streaming time before the last resume.
+	resumedAt time.Time     // Zero while paused.
+	paid      channel.Bal
+	err       error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartStream starts streaming payments to the peer of a two-party channel.
+func (c *PaymentChannel) StartStream(cfg StreamConfig) (*Stream, error) {
+	if cfg.Asset == nil {
+		cfg.Asset = c.currencies[1]
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultStreamInterval
+	}
+	if cfg.Rate == nil || cfg.Rate.Sign() <= 0 {
+		return nil, errors.New("stream rate must be positive")
+	}
+	if cfg.Budget == nil || cfg.Budget.Sign() <= 0 {
+		return nil, errors.New("stream budget must be positive")
+	}
+	if _, ok := c.ch.State().AssetIndex(cfg.Asset); !ok {
+		return nil, fmt.Errorf("asset %v not in channel", cfg.Asset)
+	}
+
+	s := &Stream{
+		ch:        c,
+		cfg:       cfg,
+		resumedAt: time.Now(),
+		paid:      big.NewInt(0),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Pause stops accruing payments until Resume is called.
+func (s *Stream) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.resumedAt.IsZero() {
+		s.active += time.Since(s.resumedAt)
+		s.resumedAt = time.Time{}
+	}
+}
+
+// Resume continues a paused stream.
+func (s *Stream) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resumedAt.IsZero() {
+		s.resumedAt = time.Now()
+	}
+}
+
+// Stop ends the stream and waits until a running update is done.
+func (s *Stream) Stop() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+}
+
+// Done is closed when the stream ended, because it was stopped, the budget
+// was exhausted or an update failed.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Paid returns the amount paid so far.
+func (s *Stream) Paid() channel.Bal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return new(big.Int).Set(s.paid)
+}
+
+// Err returns why the stream ended early, if it did.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Stream) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		amount := s.owed()
+		if amount.Sign() == 0 {
+			continue
+		}
+		if err := s.pay(amount); err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			return
+		}
+
+		s.mu.Lock()
+		s.paid.Add(s.paid, amount)
+		exhausted := s.paid.Cmp(s.cfg.Budget) >= 0
+		s.mu.Unlock()
+		if exhausted {
+			return
+		}
+	}
+}
+
+// owed returns the amount accrued and not yet paid, bounded by the budget.
+func (s *Stream) owed() channel.Bal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active := s.active
+	if !s.resumedAt.IsZero() {
+		active += time.Since(s.resumedAt)
+	}
+	accrued := new(big.Int).Mul(s.cfg.Rate, big.NewInt(int64(active)))
+	accrued.Quo(accrued, big.NewInt(int64(time.Second)))
+	if accrued.Cmp(s.cfg.Budget) > 0 {
+		accrued.Set(s.cfg.Budget)
+	}
+	owed := accrued.Sub(accrued, s.paid)
+	if owed.Sign() < 0 {
+		owed.SetInt64(0)
+	}
+	return owed
+}
+
+func (s *Stream) pay(amount channel.Bal) error {
+	ch := s.ch.ch
+	ctx, cancel := context.WithTimeout(context.Background(), streamUpdateTimeout)
+	defer cancel()
+	return ch.Update(ctx, func(state *channel.State) {
+		state.Allocation.TransferBalance(ch.Idx(), s.ch.onlyPeer(), s.cfg.Asset, amount)
+	})
+}
+
+// StreamTerms are the expected rate of a payment stream we receive.
+type StreamTerms struct {
+	Asset    channel.Asset // Streamed asset. Nil means SOL.
+	Rate     channel.Bal   // Amount paid per second.
+	Interval time.Duration // Time between updates. Zero means one second.
+	Jitter   time.Duration // Tolerated deviation of an update's timing. Zero means half the interval.
+	Budget   channel.Bal   // Budget of the stream, whose last payment may be smaller. Nil means unknown.
+}
+
+// streamCheck tracks a payment stream received in a channel.
+type streamCheck struct {
+	terms    StreamTerms
+	last     time.Time   // Time of the last accepted payment.
+	received channel.Bal // Total amount received.
+}
+
+// ExpectStream makes the client check that payments of the streamed asset in
+// the channel match the rate of the terms. Each payment must cover the time
+// since the previous one, within the jitter. A gap of more than two
+// intervals is taken as a pause, after which payments are only checked not
+// to exceed the rate.
+func (c *PaymentClient) ExpectStream(id channel.ID, terms StreamTerms) error {
+	if terms.Rate == nil || terms.Rate.Sign() <= 0 {
+		return errors.New("stream rate must be positive")
+	}
+	if terms.Asset == nil {
+		terms.Asset = c.currency[1]
+	}
+	if terms.Interval == 0 {
+		terms.Interval = defaultStreamInterval
+	}
+	if terms.Jitter == 0 {
+		terms.Jitter = terms.Interval / 2
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.streams[id] = &streamCheck{terms: terms, received: big.NewInt(0)}
+	return nil
+}
+
+// streamPayment is a checked payment of a stream, which is recorded once its
+// update is accepted.
+type streamPayment struct {
+	id    channel.ID
+	at    time.Time
+	total channel.Bal // Total amount received including the payment.
+}
+
+// checkStream checks an incoming payment against the expected stream of the
+// channel, if any. The returned payment is nil if the update does not pay
+// the stream.
+func (c *PaymentClient) checkStream(cur, next *channel.State, idx channel.Index, now time.Time) (*streamPayment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.streams[next.ID]
+	if !ok {
+		return nil, nil
+	}
+	a, ok := next.AssetIndex(s.terms.Asset)
+	if !ok {
+		return nil, nil
+	}
+	received := new(big.Int).Sub(next.Balances[a][idx], cur.Balances[a][idx])
+	if received.Sign() <= 0 {
+		return nil, nil
+	}
+
+	// The first payment covers a single interval.
+	elapsed := s.terms.Interval
+	if !s.last.IsZero() {
+		elapsed = now.Sub(s.last)
+	}
+	total := new(big.Int).Add(s.received, received)
+	if upper := rateAmount(s.terms.Rate, elapsed+s.terms.Jitter); received.Cmp(upper) > 0 {
+		return nil, fmt.Errorf("stream payment %v exceeds rate", received)
+	}
+	exhausted := s.terms.Budget != nil && total.Cmp(s.terms.Budget) >= 0
+	lower := rateAmount(s.terms.Rate, elapsed-s.terms.Jitter)
+	if elapsed <= 2*s.terms.Interval && !exhausted && received.Cmp(lower) < 0 {
+		return nil, fmt.Errorf("stream payment %v below rate, expected at least %v", received, lower)
+	}
+	return &streamPayment{id: next.ID, at: now, total: total}, nil
+}
+
+// streamPaid records the time and amount of an accepted stream payment.
+func (c *PaymentClient) streamPaid(p *streamPayment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.streams[p.id]; ok {
+		s.last = p.at
+		s.received = p.total
+	}
+}
+
+// rateAmount returns the amount paid at rate per second in d.
+func rateAmount(rate channel.Bal, d time.Duration) *big.Int {
+	if d < 0 {
+		d = 0
+	}
+	amount := new(big.Int).Mul(rate, big.NewInt(int64(d)))
+	return amount.Quo(amount, big.NewInt(int64(time.Second)))
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// OpenSubChannel opens a channel with the peer that is funded from the
+// balances of this channel instead of on-chain deposits. opts.Balances are
+// the initial balances of the sub-channel, which stay locked in this channel
+// until the sub-channel is settled back into it. A sub-channel has the assets
+// of its parent, so opts.Assets must be nil.
+func (c *PaymentChannel) OpenSubChannel(ctx context.Context, opts ChannelOptions) (*PaymentChannel, error) {
+	alloc, err := c.subAllocation(opts)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := client.NewSubChannelProposal(c.ch.ID(), opts.challengeDuration(), alloc, opts.proposalOpts()...)
+	if err != nil {
+		return nil, fmt.Errorf("creating proposal: %w", err)
+	}
+
+	log.Println("Sending sub-channel proposal", proposal)
+	ch, err := c.client.perunClient.ProposeChannel(ctx, proposal)
+	if err != nil {
+		return nil, fmt.Errorf("proposing sub-channel: %w", err)
+	}
+	return c.client.openedChannel(ch), nil
+}
+
+// VirtualPeer is the peer of a virtual channel, which is connected to the
+// same hub as we are.
+type VirtualPeer struct {
+	Address map[wallet.BackendID]wire.Address // Wire address of the peer.
+	Parent  channel.ID                        // Channel of the peer with the hub.
+	Idx     channel.Index                     // Index of the peer in its channel with the hub.
+}
+
+// VirtualPeer returns how the proposer of a virtual channel over the hub of
+// this channel refers to us.
+func (c *PaymentChannel) VirtualPeer() VirtualPeer {
+	return VirtualPeer{Address: c.client.waddress, Parent: c.ch.ID(), Idx: c.ch.Idx()}
+}
+
+// OpenVirtualChannel opens a channel with a peer with whom we have no channel
+// but who has a channel with the peer of this channel, the hub. Both sides
+// fund the virtual channel from their channel with the hub, which locks the
+// same amounts for the other side, so no on-chain deposits are needed. The
+// proposer has index 0 in the virtual channel.
+func (c *PaymentChannel) OpenVirtualChannel(ctx context.Context, peer VirtualPeer, opts ChannelOptions) (*PaymentChannel, error) {
+	alloc, err := c.subAllocation(opts)
+	if err != nil {
+		return nil, err
+	}
+	hub := c.onlyPeer()
+	// The index maps map the indices of the virtual channel to those of the
+	// parents: we are the hub in the peer's parent and vice versa.
+	indexMaps := [][]channel.Index{
+		{c.ch.Idx(), hub},
+		{1 - peer.Idx, peer.Idx},
+	}
+	proposal, err := client.NewVirtualChannelProposal(
+		opts.challengeDuration(),
+		c.client.account,
+		alloc,
+		[]map[wallet.BackendID]wire.Address{c.client.waddress, peer.Address},
+		[]channel.ID{c.ch.ID(), peer.Parent},
+		indexMaps,
+		opts.proposalOpts()...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating proposal: %w", err)
+	}
+
+	log.Println("Sending virtual channel proposal", proposal)
+	ch, err := c.client.perunClient.ProposeChannel(ctx, proposal)
+	if err != nil {
+		return nil, fmt.Errorf("proposing virtual channel: %w", err)
+	}
+	return c.client.openedChannel(ch), nil
+}
+
+// subAllocation returns the initial allocation of a channel funded from this
+// one.
+func (c *PaymentChannel) subAllocation(opts ChannelOptions) (*channel.Allocation, error) {
+	if opts.Assets != nil {
+		return nil, errors.New("sub-channel assets are those of the parent")
+	}
+	state := c.ch.State()
+	if len(opts.Balances) != len(state.Assets) {
+		return nil, fmt.Errorf("expected balances of %d assets, got %d", len(state.Assets), len(opts.Balances))
+	}
+	for a := range opts.Balances {
+		if len(opts.Balances[a]) != state.NumParts() {
+			return nil, fmt.Errorf("expected %d balances of asset %d, got %d", state.NumParts(), a, len(opts.Balances[a]))
+		}
+	}
+	alloc := channel.NewAllocation(state.NumParts(), state.Backends, state.Assets...)
+	alloc.Balances = opts.Balances.Clone()
+	return alloc, nil
+}
@@ -15,6 +15,7 @@
 package client
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -28,16 +29,59 @@ import (
 )
 
 const (
-	txFinalityDepth = 1 // Number of blocks required to confirm a transaction.
+	txFinalityDepth = 1 // Default number of blocks required to confirm a transaction.
+
+	// maxParticipants is the maximum number of channel participants. Payments
+	// by index and the update validation work for any number of participants,
+	// but the channel proposal protocol of go-perun only supports two parties,
+	// and swaps, invoices, the hub and the order book assume two.
+	maxParticipants = 2
+
+	// maxChallengeDuration is the longest challenge duration in seconds of
+	// the channels we accept, which bounds how long our funds can be locked
+	// in a dispute.
+	maxChallengeDuration = 7 * 24 * 60 * 60
+
+	// acceptQueueSize is the number of accepted channels queued for
+	// AcceptedChannel and AcceptedSubChannel.
+	acceptQueueSize = 16
 )
 
-// CreateContractBackend creates a new contract backend.
+// CreateContractBackend creates a new contract backend using the default gas
+// configuration.
 func CreateContractBackend(
 	nodeURL string,
 	chainID uint64,
 	w *swallet.Wallet,
 ) (ethchannel.ContractBackend, error) {
-	signer := types.LatestSignerForChainID(new(big.Int).SetUint64(chainID))
+	return CreateContractBackendWithGas(nodeURL, chainID, w, DefaultGasConfig())
+}
+
+// CreateContractBackendWithGas creates a new contract backend whose
+// transactions use the given gas configuration. Transactions are resubmitted
+// for as long as the process runs.
+func CreateContractBackendWithGas(
+	nodeURL string,
+	chainID uint64,
+	w *swallet.Wallet,
+	gas GasConfig,
+) (ethchannel.ContractBackend, error) {
+	return createContractBackend(context.Background(), nodeURL, chainID, w, gas, txFinalityDepth)
+}
+
+// createContractBackend creates a new contract backend that waits for the
+// given number of confirmations of each transaction. Resubmitting transactions
+// stops when the context is done.
+func createContractBackend(
+	ctx context.Context,
+	nodeURL string,
+	chainID uint64,
+	w *swallet.Wallet,
+	gas GasConfig,
+	finalityDepth uint64,
+) (ethchannel.ContractBackend, error) {
+	id := new(big.Int).SetUint64(chainID)
+	signer := types.LatestSignerForChainID(id)
 	transactor := swallet.NewTransactor(w, signer)
 
 	ethClient, err := ethclient.Dial(nodeURL)
@@ -45,7 +89,13 @@ func CreateContractBackend(
 		return ethchannel.ContractBackend{}, err
 	}
 
-	return ethchannel.NewContractBackend(ethClient, ethchannel.MakeChainID(big.NewInt(int64(chainID))), transactor, txFinalityDepth), nil
+	var ci ethchannel.ContractInterface = ethClient
+	if gas.BumpAfter > 0 && gas.MaxBumps > 0 {
+		ci = newBumpingBackend(ctx, ethClient, transactor, signer, gas)
+	}
+	tr := &gasTransactor{tr: transactor, cfg: gas, chainID: id}
+
+	return ethchannel.NewContractBackend(ci, ethchannel.MakeChainID(id), tr, finalityDepth), nil
 }
 
 // WalletAddress returns the wallet address of the client.
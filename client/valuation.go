@@ -0,0 +1,121 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/sol-eth-cross-chain-demo/oracle"
+)
+
+const priceTimeout = 10 * time.Second
+
+// currencyUnit is the symbol of the currency of a chain and the number of
+// decimals of its smallest unit.
+type currencyUnit struct {
+	symbol   string
+	decimals int64
+	base     string // Name of the smallest unit.
+}
+
+var currencyUnits = map[wallet.BackendID]currencyUnit{
+	1: {"ETH", 18, "wei"},
+	6: {"SOL", 9, "lamports"},
+}
+
+// Quote returns the amount of the currency of chain to that amount of the
+// currency of chain from is worth at the price of the oracle, both in their
+// smallest units.
+func (c *PaymentClient) Quote(ctx context.Context, from, to wallet.BackendID, amount *big.Int) (*big.Int, error) {
+	return quote(ctx, c.oracle, from, to, amount)
+}
+
+// quote values amount at the price of the given oracle, see Quote.
+func quote(ctx context.Context, feed oracle.Oracle, from, to wallet.BackendID, amount *big.Int) (*big.Int, error) {
+	if feed == nil {
+		return nil, errors.New("no price oracle")
+	}
+	fromUnit, ok := currencyUnits[from]
+	if !ok {
+		return nil, fmt.Errorf("unknown chain %d", from)
+	}
+	toUnit, ok := currencyUnits[to]
+	if !ok {
+		return nil, fmt.Errorf("unknown chain %d", to)
+	}
+	price, err := feed.Price(ctx, fromUnit.symbol, toUnit.symbol)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Rat).SetInt(amount)
+	value.Mul(value, price)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toUnit.decimals), pow10(fromUnit.decimals)))
+	return new(big.Int).Quo(value.Num(), value.Denom()), nil
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
+}
+
+// checkSwap checks whether an update of a two-party channel swaps one asset
+// of the receiving participant for another. If so, the received amount must
+// be worth the given amount at the oracle's price, within the slippage
+// tolerance. Without an oracle, no update is a swap.
+func checkSwap(feed oracle.Oracle, slippage *big.Rat, cur *channel.State, next client.ChannelUpdate) (bool, error) {
+	if feed == nil || cur.NumParts() != 2 {
+		return false, nil
+	}
+	receiver := 1 - next.ActorIdx
+	given, received := -1, -1
+	for a := range cur.Assets {
+		switch next.State.Balances[a][receiver].Cmp(cur.Balances[a][receiver]) {
+		case -1:
+			if given >= 0 {
+				return false, nil
+			}
+			given = a
+		case 1:
+			if received >= 0 {
+				return false, nil
+			}
+			received = a
+		}
+	}
+	if given < 0 || received < 0 {
+		return false, nil
+	}
+
+	givenAmount := new(big.Int).Sub(cur.Balances[given][receiver], next.State.Balances[given][receiver])
+	receivedAmount := new(big.Int).Sub(next.State.Balances[received][receiver], cur.Balances[received][receiver])
+	ctx, cancel := context.WithTimeout(context.Background(), priceTimeout)
+	defer cancel()
+	worth, err := quote(ctx, feed, backendOf(cur.Assets[given]), backendOf(cur.Assets[received]), givenAmount)
+	if err != nil {
+		return true, fmt.Errorf("valuing swap: %v", err)
+	}
+	minimum := new(big.Rat).SetInt(worth)
+	minimum.Mul(minimum, new(big.Rat).Sub(big.NewRat(1, 1), slippage))
+	if new(big.Rat).SetInt(receivedAmount).Cmp(minimum) < 0 {
+		return true, fmt.Errorf("swap outside slippage tolerance: received %v for %v worth %v", receivedAmount, givenAmount, worth)
+	}
+	return true, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	solchannel "github.com/perun-network/perun-solana-backend/channel"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/client"
+	"perun.network/sol-eth-cross-chain-demo/oracle"
+)
+
+func TestCheckSwap(t *testing.T) {
+	// 1 ETH = 20 SOL, so 1e9 wei are worth 20 lamports.
+	feed := oracle.Static{"ETH/SOL": big.NewRat(20, 1)}
+	assets := []channel.Asset{ethchannel.NewAsset(big.NewInt(1337), common.Address{}), solchannel.NewSOLSolanaCrossAsset()}
+	state := func(wei0, wei1, lamports0, lamports1 int64) *channel.State {
+		return &channel.State{Allocation: channel.Allocation{
+			Assets: assets,
+			Balances: channel.Balances{
+				{big.NewInt(wei0), big.NewInt(wei1)},
+				{big.NewInt(lamports0), big.NewInt(lamports1)},
+			},
+		}}
+	}
+	// The peer (actor 0) takes 1e9 wei of ours and pays us in lamports.
+	cur := state(0, 2e9, 100, 0)
+
+	tests := []struct {
+		name     string
+		feed     oracle.Oracle
+		slippage *big.Rat
+		next     *channel.State
+		swap     bool
+		valid    bool
+	}{
+		{"fair swap", feed, new(big.Rat), state(1e9, 1e9, 80, 20), true, true},
+		{"overpaid swap", feed, new(big.Rat), state(1e9, 1e9, 70, 30), true, true},
+		{"underpaid swap", feed, new(big.Rat), state(1e9, 1e9, 81, 19), true, false},
+		{"underpaid within slippage", feed, big.NewRat(1, 10), state(1e9, 1e9, 82, 18), true, true},
+		{"underpaid beyond slippage", feed, big.NewRat(1, 10), state(1e9, 1e9, 83, 17), true, false},
+		{"payment", feed, new(big.Rat), state(0, 2e9, 90, 10), false, true},
+		{"withdrawal", feed, new(big.Rat), state(1e9, 1e9, 100, 0), false, true},
+		{"without oracle", nil, new(big.Rat), state(1e9, 1e9, 100, 0), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swap, err := checkSwap(tt.feed, tt.slippage, cur, client.ChannelUpdate{State: tt.next, ActorIdx: 0})
+			if swap != tt.swap {
+				t.Errorf("swap = %t, want %t", swap, tt.swap)
+			}
+			if tt.valid && err != nil {
+				t.Errorf("swap rejected: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("swap accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/watcher"
+	"perun.network/sol-eth-cross-chain-demo/history"
+)
+
+// Watchtower watches channels on behalf of an offline client and refutes the
+// registration of outdated states.
+type Watchtower interface {
+	// Watch hands over the channel with its latest signed state.
+	Watch(ctx context.Context, state channel.SignedState) error
+}
+
+const delegateTimeout = 30 * time.Second
+
+// DelegateWatching registers all open channels with the watchtower and sends
+// it every new state, so that it can refute outdated states while the client
+// is offline. Channels keep being watched locally as well.
+func (c *PaymentClient) DelegateWatching(ctx context.Context, tower Watchtower) error {
+	for _, state := range c.watcher.delegate(tower) {
+		if err := tower.Watch(ctx, state); err != nil {
+			return fmt.Errorf("registering channel %x: %w", state.State.ID, err)
+		}
+	}
+	return nil
+}
+
+// recordingWatcher wraps a watcher and keeps the latest signed state of each
+// watched ledger channel, so that it can be handed over to a watchtower.
+type recordingWatcher struct {
+	watcher.Watcher
+
+	mu     sync.Mutex
+	latest map[channel.ID]channel.SignedState
+	tower  Watchtower // Receives all new states, if set.
+
+	history *history.Store // Records all states, if set.
+}
+
+func newRecordingWatcher(w watcher.Watcher, hist *history.Store) *recordingWatcher {
+	return &recordingWatcher{Watcher: w, latest: make(map[channel.ID]channel.SignedState), history: hist}
+}
+
+// StartWatchingLedgerChannel implements watcher.Watcher.
+func (w *recordingWatcher) StartWatchingLedgerChannel(ctx context.Context, state channel.SignedState) (watcher.StatesPub, watcher.AdjudicatorSub, error) {
+	pub, sub, err := w.Watcher.StartWatchingLedgerChannel(ctx, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.record(state)
+	return &recordingPub{StatesPub: pub, watcher: w, params: state.Params}, sub, nil
+}
+
+// StopWatching implements watcher.Watcher.
+func (w *recordingWatcher) StopWatching(ctx context.Context, id channel.ID) error {
+	w.mu.Lock()
+	delete(w.latest, id)
+	w.mu.Unlock()
+	return w.Watcher.StopWatching(ctx, id)
+}
+
+func (w *recordingWatcher) record(state channel.SignedState) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.latest[state.State.ID] = state
+	if w.history != nil {
+		if err := w.history.Append(state); err != nil {
+			log.Printf("Recording state of channel %x failed: %v", state.State.ID, err)
+		}
+	}
+	if w.tower == nil {
+		return
+	}
+
+	// The watchtower ignores states older than its latest one, so they
+	// may arrive out of order.
+	go func(tower Watchtower) {
+		ctx, cancel := context.WithTimeout(context.Background(), delegateTimeout)
+		defer cancel()
+		if err := tower.Watch(ctx, state); err != nil {
+			log.Printf("Sending state of channel %x to watchtower failed: %v", state.State.ID, err)
+		}
+	}(w.tower)
+}
+
+// delegate sets the watchtower and returns the latest states of all watched
+// channels.
+func (w *recordingWatcher) delegate(tower Watchtower) []channel.SignedState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tower = tower
+	states := make([]channel.SignedState, 0, len(w.latest))
+	for _, state := range w.latest {
+		states = append(states, state)
+	}
+	return states
+}
+
+// latestState returns the latest signed state of the channel.
+func (w *recordingWatcher) latestState(id channel.ID) (channel.SignedState, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	state, ok := w.latest[id]
+	return state, ok
+}
+
+// recordingPub records each published transaction before publishing it.
+type recordingPub struct {
+	watcher.StatesPub
+	watcher *recordingWatcher
+	params  *channel.Params
+}
+
+// Publish implements watcher.StatesPub.
+func (p *recordingPub) Publish(ctx context.Context, tx channel.Transaction) error {
+	p.watcher.record(channel.SignedState{Params: p.params, State: tx.State, Sigs: tx.Sigs})
+	return p.StatesPub.Publish(ctx, tx)
+}
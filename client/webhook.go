@@ -0,0 +1,90 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"math/big"
+
+	"perun.network/go-perun/channel"
+	"perun.network/sol-eth-cross-chain-demo/webhook"
+)
+
+// SetWebhooks sets the dispatcher that receives notifications about opened
+// channels, incoming payments and disputes. A nil dispatcher disables them.
+func (c *PaymentClient) SetWebhooks(d *webhook.Dispatcher) {
+	c.webhooks.Store(d)
+}
+
+// notify sends a webhook notification if a dispatcher is set.
+func (c *PaymentClient) notify(event string, id channel.ID, data map[string]interface{}) {
+	d := c.webhooks.Load()
+	if d == nil {
+		return
+	}
+	d.Notify(event, fmt.Sprintf("%x", id), data)
+}
+
+// notifyPayment notifies about the balance increase of the receiver caused by
+// an accepted update.
+func (c *PaymentClient) notifyPayment(cur, next *channel.State, receiverIdx channel.Index) {
+	received := make(map[string]interface{})
+	for _, asset := range next.Assets {
+		diff := new(big.Int).Sub(
+			next.Allocation.Balance(receiverIdx, asset),
+			cur.Allocation.Balance(receiverIdx, asset),
+		)
+		if diff.Sign() > 0 {
+			received[c.assetName(asset)] = diff.String()
+		}
+	}
+	if len(received) == 0 {
+		return
+	}
+	c.notify(webhook.PaymentReceived, next.ID, map[string]interface{}{
+		"version":  next.Version,
+		"received": received,
+	})
+}
+
+// notifyAdjudicatorEvent notifies about on-chain disputes. A registered state
+// that is older than our latest state is reported as stale.
+func (c *PaymentClient) notifyAdjudicatorEvent(e channel.AdjudicatorEvent) {
+	switch e.(type) {
+	case *channel.RegisteredEvent, *channel.ProgressedEvent:
+		data := map[string]interface{}{"version": e.Version()}
+		ch, err := c.perunClient.Channel(e.ID())
+		if err == nil && ch.State().Version > e.Version() {
+			data["latest_version"] = ch.State().Version
+			c.notify(webhook.StaleStateRegistered, e.ID(), data)
+			return
+		}
+		c.notify(webhook.DisputeRegistered, e.ID(), data)
+	case *channel.ConcludedEvent:
+		c.notify(webhook.ChannelConcluded, e.ID(), map[string]interface{}{"version": e.Version()})
+	}
+}
+
+// assetName returns a readable name of one of the channel currencies.
+func (c *PaymentClient) assetName(asset channel.Asset) string {
+	switch {
+	case asset.Equal(c.currency[0]):
+		return "ETH"
+	case asset.Equal(c.currency[1]):
+		return "SOL"
+	default:
+		return fmt.Sprintf("%v", asset)
+	}
+}
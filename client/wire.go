@@ -0,0 +1,82 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+)
+
+// Message types of the protocols of this package, which go-perun relays
+// between the clients but does not handle itself.
+const (
+	invoiceMsgType wire.Type = wire.LastType + 32 + iota
+	invoicePaymentMsgType
+	forwardMsgType
+	orderMsgType
+	orderCancelMsgType
+	orderFillMsgType
+)
+
+// msgRouter is a wire.Bus that delivers the messages of the protocols of
+// this package to their handlers and all other messages to the Perun client.
+type msgRouter struct {
+	wire.Bus
+
+	mu       sync.RWMutex
+	handlers map[wire.Type]func(*wire.Envelope)
+}
+
+func newMsgRouter(bus wire.Bus) *msgRouter {
+	return &msgRouter{Bus: bus, handlers: make(map[wire.Type]func(*wire.Envelope))}
+}
+
+// handle sets the handler of messages of the given type.
+func (r *msgRouter) handle(t wire.Type, h func(*wire.Envelope)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[t] = h
+}
+
+// SubscribeClient implements wire.Bus.
+func (r *msgRouter) SubscribeClient(c wire.Consumer, addr map[wallet.BackendID]wire.Address) error {
+	return r.Bus.SubscribeClient(&routingConsumer{Consumer: c, router: r}, addr)
+}
+
+// send publishes a message from sender to recipient.
+func (r *msgRouter) send(ctx context.Context, msg wire.Msg, sender, recipient map[wallet.BackendID]wire.Address) error {
+	return r.Publish(ctx, &wire.Envelope{Sender: sender, Recipient: recipient, Msg: msg})
+}
+
+// routingConsumer intercepts the messages with a handler.
+type routingConsumer struct {
+	wire.Consumer
+	router *msgRouter
+}
+
+// Put implements wire.Consumer.
+func (c *routingConsumer) Put(e *wire.Envelope) {
+	c.router.mu.RLock()
+	h, ok := c.router.handlers[e.Msg.Type()]
+	c.router.mu.RUnlock()
+	if ok {
+		h(e)
+		return
+	}
+	c.Consumer.Put(e)
+}
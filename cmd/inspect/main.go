@@ -0,0 +1,211 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command inspect decodes wire-encoded channel parameters, states and signed
+// transactions, prints them and verifies the participants' signatures.
+//
+// Usage:
+//
+//	inspect [-hex] -params <file> [-state <file> | -tx <file>]
+//	inspect [-hex] -signed <file>
+//
+// A signed file contains the parameters followed by a transaction, as sent
+// to the watchtower.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+
+	// Register the wallet and channel backends to decode and verify states.
+	_ "github.com/perun-network/perun-eth-backend/channel"
+	_ "github.com/perun-network/perun-eth-backend/wallet"
+	_ "github.com/perun-network/perun-solana-backend/channel"
+	_ "github.com/perun-network/perun-solana-backend/wallet"
+)
+
+// backendNames are the names of the wallet backends of the demo.
+var backendNames = map[wallet.BackendID]string{1: "ethereum", 6: "solana"}
+
+func main() {
+	isHex := flag.Bool("hex", false, "inputs are hex encoded")
+	paramsFile := flag.String("params", "", "file with encoded channel.Params")
+	stateFile := flag.String("state", "", "file with encoded channel.State")
+	txFile := flag.String("tx", "", "file with encoded channel.Transaction")
+	signedFile := flag.String("signed", "", "file with encoded channel.Params followed by a channel.Transaction")
+	flag.Parse()
+
+	read := func(path string) io.Reader {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fail("reading %s: %v", path, err)
+		}
+		if *isHex {
+			s := strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")
+			if data, err = hex.DecodeString(s); err != nil {
+				fail("decoding hex in %s: %v", path, err)
+			}
+		}
+		return bytes.NewReader(data)
+	}
+
+	var params *channel.Params
+	var tx channel.Transaction
+	switch {
+	case *signedFile != "":
+		r := read(*signedFile)
+		params = decodeParams(r)
+		tx = decodeTx(r)
+	case *paramsFile != "":
+		params = decodeParams(read(*paramsFile))
+		if *stateFile != "" {
+			tx.State = new(channel.State)
+			if err := tx.State.Decode(read(*stateFile)); err != nil {
+				fail("decoding state: %v", err)
+			}
+		} else if *txFile != "" {
+			tx = decodeTx(read(*txFile))
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	printParams(params)
+	if tx.State == nil {
+		return
+	}
+	printState(tx.State)
+	ok := tx.State.ID == params.ID()
+	fmt.Printf("Channel ID matches params: %v\n", ok)
+	if tx.Sigs != nil && !verify(params, tx) {
+		ok = false
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func decodeParams(r io.Reader) *channel.Params {
+	var params channel.Params
+	if err := params.Decode(r); err != nil {
+		fail("decoding params: %v", err)
+	}
+	id, err := channel.CalcID(&params)
+	if err != nil {
+		fail("computing channel ID: %v", err)
+	}
+	if id != params.ID() {
+		fail("recomputed channel ID %x differs from %x", id, params.ID())
+	}
+	return &params
+}
+
+func decodeTx(r io.Reader) channel.Transaction {
+	var tx channel.Transaction
+	if err := tx.Decode(r); err != nil {
+		fail("decoding transaction: %v", err)
+	}
+	if tx.State == nil {
+		fail("transaction without state")
+	}
+	return tx
+}
+
+func printParams(p *channel.Params) {
+	fmt.Println("Params")
+	fmt.Printf("  Channel ID:         %x\n", p.ID())
+	fmt.Printf("  Challenge duration: %d\n", p.ChallengeDuration)
+	fmt.Printf("  Nonce:              %v\n", p.Nonce)
+	fmt.Printf("  Ledger channel:     %v\n", p.LedgerChannel)
+	fmt.Printf("  Virtual channel:    %v\n", p.VirtualChannel)
+	fmt.Printf("  App:                %v\n", appName(p.App))
+	for i, part := range p.Parts {
+		fmt.Printf("  Participant %d\n", i)
+		for id, addr := range part {
+			fmt.Printf("    %-9s %v\n", backendName(id)+":", addr)
+		}
+	}
+}
+
+func printState(s *channel.State) {
+	fmt.Println("State")
+	fmt.Printf("  Channel ID: %x\n", s.ID)
+	fmt.Printf("  Version:    %d\n", s.Version)
+	fmt.Printf("  Final:      %v\n", s.IsFinal)
+	fmt.Printf("  App:        %v\n", appName(s.App))
+	for a, asset := range s.Assets {
+		fmt.Printf("  Asset %d (%s): %v\n", a, backendName(s.Backends[a]), asset)
+		for i, bal := range s.Balances[a] {
+			fmt.Printf("    Participant %d: %v\n", i, bal)
+		}
+	}
+	for _, sub := range s.Locked {
+		fmt.Printf("  Locked in %x: %v\n", sub.ID, sub.Bals)
+	}
+}
+
+// verify checks the signature of each participant with all its addresses.
+func verify(params *channel.Params, tx channel.Transaction) bool {
+	ok := true
+	fmt.Println("Signatures")
+	for i, part := range params.Parts {
+		if i >= len(tx.Sigs) || tx.Sigs[i] == nil {
+			fmt.Printf("  Participant %d: missing\n", i)
+			ok = false
+			continue
+		}
+		for id, addr := range part {
+			valid, err := channel.Verify(addr, tx.State, tx.Sigs[i])
+			switch {
+			case err != nil:
+				fmt.Printf("  Participant %d (%s): error: %v\n", i, backendName(id), err)
+				ok = false
+			case !valid:
+				fmt.Printf("  Participant %d (%s): INVALID\n", i, backendName(id))
+				ok = false
+			default:
+				fmt.Printf("  Participant %d (%s): valid\n", i, backendName(id))
+			}
+		}
+	}
+	return ok
+}
+
+func appName(app channel.App) string {
+	if channel.IsNoApp(app) {
+		return "none"
+	}
+	return fmt.Sprint(app.Def())
+}
+
+func backendName(id wallet.BackendID) string {
+	if name, ok := backendNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("backend %d", id)
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
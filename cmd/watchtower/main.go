@@ -0,0 +1,77 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command watchtower runs a watchtower that watches channels registered by
+// offline clients on Ethereum and Solana and refutes outdated states.
+package main
+
+import (
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	swallet "github.com/perun-network/perun-eth-backend/wallet/simple"
+	solchannel "github.com/perun-network/perun-solana-backend/channel"
+	soladjudicator "github.com/perun-network/perun-solana-backend/channel/adjudicator"
+	"perun.network/go-perun/channel/multi"
+	"perun.network/sol-eth-cross-chain-demo/client"
+	"perun.network/sol-eth-cross-chain-demo/watchtower"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	listen := flag.String("listen", "127.0.0.1:8600", "address to listen on")
+	nodeURL := flag.String("eth-node", "ws://127.0.0.1:8545", "Ethereum node URL")
+	chainID := flag.Uint64("chain-id", 1337, "Ethereum chain ID")
+	adjudicator := flag.String("adjudicator", "", "address of the Ethereum adjudicator")
+	flag.Parse()
+
+	// The key pays for refutations on Ethereum, the token authenticates clients.
+	key, err := crypto.HexToECDSA(os.Getenv("WATCHTOWER_KEY"))
+	if err != nil {
+		log.Fatalf("Invalid WATCHTOWER_KEY: %v", err)
+	}
+	if !common.IsHexAddress(*adjudicator) {
+		log.Fatalf("Invalid adjudicator address: %q", *adjudicator)
+	}
+
+	w := swallet.NewWallet(key)
+	acc := crypto.PubkeyToAddress(key.PublicKey)
+	cb, err := client.CreateContractBackend(*nodeURL, *chainID, w)
+	if err != nil {
+		log.Fatalf("Failed to create contract backend: %v", err)
+	}
+
+	// The Solana side of channels is not watched: the Solana backend does
+	// not implement registering states, so its adjudicator is only used to
+	// subscribe to events and refutations on Solana return an error.
+	adj := multi.NewAdjudicator()
+	ethAdj := ethchannel.NewAdjudicator(cb, common.HexToAddress(*adjudicator), acc, accounts.Account{Address: acc}, 1000000)
+	adj.RegisterAdjudicator(ethchannel.MakeLedgerBackendID(new(big.Int).SetUint64(*chainID)), ethAdj)
+	adj.RegisterAdjudicator(solchannel.MakeCCID(solchannel.MakeContractID("6")), client.NewSubscribeOnlyAdjudicator(soladjudicator.NewAdjudicator(), 6))
+
+	server, err := watchtower.NewServer(adj, os.Getenv("WATCHTOWER_TOKEN"))
+	if err != nil {
+		log.Fatalf("Failed to create watchtower: %v", err)
+	}
+	log.Printf("Watchtower listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, server))
+}
@@ -75,6 +75,7 @@ func SetupPaymentClient(
 	solAsset channel.Asset,
 	solFunder *solFunder.Funder,
 	solAdj *solAdjudicator.Adjudicator,
+	opts ...client.Option,
 ) *client.PaymentClient {
 	// Create wallet and account.
 	w := swallet.NewWallet(k)
@@ -96,6 +97,7 @@ func SetupPaymentClient(
 		solAsset,
 		solFunder,
 		solAdj,
+		opts...,
 	)
 	if err != nil {
 		panic(err)
@@ -117,3 +119,20 @@ func NewBalanceLogger(chainURL string) balanceLogger {
 	}
 	return balanceLogger{ethClient: c}
 }
+
+// SetupSimulationClient creates a client of the given key that only simulates
+// operations, without connecting to the node.
+func SetupSimulationClient(
+	asset ethwallet.Address,
+	k *ecdsa.PrivateKey,
+	solAccount *solWallet.Account,
+	solAsset channel.Asset,
+	opts ...client.Option,
+) *client.PaymentClient {
+	eaddr := ethwallet.AsWalletAddr(crypto.PubkeyToAddress(k.PublicKey))
+	c, err := client.NewSimulationClient(eaddr, 1337, asset, solAccount, solAsset, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// Bundle is a set of channel histories signed by the exporting operator.
+type Bundle struct {
+	Created   time.Time      `json:"created"`
+	Channels  []*History     `json:"channels"`
+	Signer    common.Address `json:"signer"`
+	Signature []byte         `json:"signature"` // Signature of the bundle's digest.
+}
+
+// NewBundle creates an audit bundle of the histories signed with the key.
+func NewBundle(key *ecdsa.PrivateKey, histories ...*History) (*Bundle, error) {
+	b := &Bundle{
+		Created:  time.Now().UTC(),
+		Channels: histories,
+		Signer:   crypto.PubkeyToAddress(key.PublicKey),
+	}
+	digest, err := b.digest()
+	if err != nil {
+		return nil, err
+	}
+	if b.Signature, err = crypto.Sign(digest, key); err != nil {
+		return nil, errors.WithMessage(err, "signing bundle")
+	}
+	return b, nil
+}
+
+// Verify checks the operator's signature and all channel histories.
+func (b *Bundle) Verify() error {
+	digest, err := b.digest()
+	if err != nil {
+		return err
+	}
+	pub, err := crypto.SigToPub(digest, b.Signature)
+	if err != nil {
+		return errors.WithMessage(err, "recovering signer")
+	}
+	if crypto.PubkeyToAddress(*pub) != b.Signer {
+		return errors.New("bundle not signed by its signer")
+	}
+	for _, h := range b.Channels {
+		if err := h.Verify(); err != nil {
+			return fmt.Errorf("channel %s: %w", h.ChannelID, err)
+		}
+	}
+	return nil
+}
+
+// digest returns the hash of the bundle without its signature.
+func (b *Bundle) digest() ([]byte, error) {
+	unsigned := *b
+	unsigned.Signature = nil
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, errors.WithMessage(err, "encoding bundle")
+	}
+	return crypto.Keccak256(data), nil
+}
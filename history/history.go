@@ -0,0 +1,289 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package history records the sequence of signed states of each channel in
+// an append-only log and exports it for auditing.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// Entry is a signed state of a channel.
+type Entry struct {
+	Version  uint64       `json:"version"`
+	Time     time.Time    `json:"time"`
+	IsFinal  bool         `json:"isFinal"`
+	Balances [][]string   `json:"balances"` // Balance per asset and participant.
+	State    []byte       `json:"state"`    // Wire encoding of the state.
+	Sigs     []wallet.Sig `json:"sigs"`
+}
+
+// History is the sequence of signed states of a channel.
+type History struct {
+	ChannelID string  `json:"channelId"`
+	Params    []byte  `json:"params"` // Wire encoding of the channel parameters.
+	Entries   []Entry `json:"entries"`
+}
+
+// Store keeps the history of each channel in two files in a directory: the
+// encoded channel parameters and a JSON line per state.
+type Store struct {
+	dir string
+
+	mu     sync.Mutex
+	latest map[channel.ID]uint64 // Latest recorded version per channel.
+}
+
+// NewStore creates a store in the given directory.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.WithMessage(err, "creating history directory")
+	}
+	return &Store{dir: dir, latest: make(map[channel.ID]uint64)}, nil
+}
+
+// Append records a signed state. States that are not newer than the latest
+// recorded state of the channel are ignored.
+func (s *Store) Append(state channel.SignedState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := state.State.ID
+	latest, ok := s.latest[id]
+	if !ok {
+		var err error
+		if latest, ok, err = s.loadLatest(id); err != nil {
+			return err
+		}
+	}
+	if ok && state.State.Version <= latest {
+		return nil
+	}
+
+	if _, err := os.Stat(s.paramsPath(id)); os.IsNotExist(err) {
+		var params bytes.Buffer
+		if err := state.Params.Encode(&params); err != nil {
+			return errors.WithMessage(err, "encoding params")
+		}
+		if err := os.WriteFile(s.paramsPath(id), params.Bytes(), 0o600); err != nil {
+			return errors.WithMessage(err, "writing params")
+		}
+	}
+
+	entry, err := newEntry(state)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithMessage(err, "encoding entry")
+	}
+	f, err := os.OpenFile(s.entriesPath(id), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.WithMessage(err, "opening history")
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return errors.WithMessage(err, "writing entry")
+	}
+	s.latest[id] = state.State.Version
+	return nil
+}
+
+// Load returns the history of a channel.
+func (s *Store) Load(id channel.ID) (*History, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params, err := os.ReadFile(s.paramsPath(id))
+	if err != nil {
+		return nil, errors.WithMessage(err, "reading params")
+	}
+	entries, err := s.readEntries(id)
+	if err != nil {
+		return nil, err
+	}
+	return &History{ChannelID: hex.EncodeToString(id[:]), Params: params, Entries: entries}, nil
+}
+
+func (s *Store) loadLatest(id channel.ID) (uint64, bool, error) {
+	entries, err := s.readEntries(id)
+	if err != nil || len(entries) == 0 {
+		return 0, false, err
+	}
+	return entries[len(entries)-1].Version, true, nil
+}
+
+func (s *Store) readEntries(id channel.ID) ([]Entry, error) {
+	f, err := os.Open(s.entriesPath(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "opening history")
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, errors.WithMessagef(err, "decoding entry %d", len(entries))
+		}
+		entries = append(entries, e)
+	}
+	return entries, errors.WithMessage(scanner.Err(), "reading history")
+}
+
+func (s *Store) paramsPath(id channel.ID) string {
+	return filepath.Join(s.dir, hex.EncodeToString(id[:])+".params")
+}
+
+func (s *Store) entriesPath(id channel.ID) string {
+	return filepath.Join(s.dir, hex.EncodeToString(id[:])+".jsonl")
+}
+
+func newEntry(state channel.SignedState) (Entry, error) {
+	var enc bytes.Buffer
+	if err := state.State.Encode(&enc); err != nil {
+		return Entry{}, errors.WithMessage(err, "encoding state")
+	}
+	balances := make([][]string, len(state.State.Balances))
+	for a, bals := range state.State.Balances {
+		balances[a] = make([]string, len(bals))
+		for i, bal := range bals {
+			balances[a][i] = bal.String()
+		}
+	}
+	return Entry{
+		Version:  state.State.Version,
+		Time:     time.Now().UTC(),
+		IsFinal:  state.State.IsFinal,
+		Balances: balances,
+		State:    enc.Bytes(),
+		Sigs:     wallet.CloneSigs(state.Sigs),
+	}, nil
+}
+
+// WriteJSON writes the history as JSON.
+func (h *History) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h)
+}
+
+// WriteCSV writes a row per state with the balance of each participant per
+// asset.
+func (h *History) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"version", "time", "final"}
+	if len(h.Entries) > 0 {
+		for a, bals := range h.Entries[0].Balances {
+			for i := range bals {
+				header = append(header, fmt.Sprintf("asset%d_part%d", a, i))
+			}
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range h.Entries {
+		row := []string{strconv.FormatUint(e.Version, 10), e.Time.Format(time.RFC3339), strconv.FormatBool(e.IsFinal)}
+		for _, bals := range e.Balances {
+			row = append(row, bals...)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Verify checks that every state belongs to the channel, that the versions
+// increase and that all states are signed by all participants.
+func (h *History) Verify() error {
+	var params channel.Params
+	if err := params.Decode(bytes.NewReader(h.Params)); err != nil {
+		return errors.WithMessage(err, "decoding params")
+	}
+	id := params.ID()
+	if hex.EncodeToString(id[:]) != h.ChannelID {
+		return errors.New("params do not match channel ID")
+	}
+
+	for i, e := range h.Entries {
+		var state channel.State
+		if err := state.Decode(bytes.NewReader(e.State)); err != nil {
+			return errors.WithMessagef(err, "decoding state %d", i)
+		}
+		if state.ID != id {
+			return fmt.Errorf("state %d belongs to another channel", i)
+		}
+		if state.Version != e.Version || state.IsFinal != e.IsFinal || !balancesMatch(state.Balances, e.Balances) {
+			return fmt.Errorf("state %d does not match its summary", i)
+		}
+		if i > 0 && e.Version <= h.Entries[i-1].Version {
+			return fmt.Errorf("state %d does not increase the version", i)
+		}
+		if len(e.Sigs) != len(params.Parts) {
+			return fmt.Errorf("state %d has %d signatures, expected %d", i, len(e.Sigs), len(params.Parts))
+		}
+		for p, part := range params.Parts {
+			for _, addr := range part {
+				ok, err := channel.Verify(addr, &state, e.Sigs[p])
+				if err != nil {
+					return errors.WithMessagef(err, "verifying signature of participant %d on state %d", p, i)
+				} else if !ok {
+					return fmt.Errorf("invalid signature of participant %d on state %d", p, i)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func balancesMatch(bals channel.Balances, summary [][]string) bool {
+	if len(bals) != len(summary) {
+		return false
+	}
+	for a := range bals {
+		if len(bals[a]) != len(summary[a]) {
+			return false
+		}
+		for i, bal := range bals[a] {
+			if bal.String() != summary[a][i] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	ethwallet "github.com/perun-network/perun-eth-backend/wallet"
+	"github.com/perun-network/perun-eth-backend/wallet/simple"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+const ethBackend wallet.BackendID = 1
+
+// testChannel is a two-party ETH channel whose states are signed by both
+// participants.
+type testChannel struct {
+	t        *testing.T
+	params   *channel.Params
+	accounts []wallet.Account
+}
+
+func newTestChannel(t *testing.T) *testChannel {
+	t.Helper()
+	c := &testChannel{t: t}
+	parts := make([]map[wallet.BackendID]wallet.Address, 2)
+	for i := range parts {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := ethwallet.AsWalletAddr(crypto.PubkeyToAddress(key.PublicKey))
+		acc, err := simple.NewWallet(key).Unlock(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[i] = map[wallet.BackendID]wallet.Address{ethBackend: addr}
+		c.accounts = append(c.accounts, acc)
+	}
+	params, err := channel.NewParams(60, parts, channel.NoApp(), big.NewInt(1), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.params = params
+	return c
+}
+
+// state returns the state of the given version signed by the accounts.
+func (c *testChannel) state(version uint64, bal0, bal1 int64, signers ...wallet.Account) channel.SignedState {
+	c.t.Helper()
+	asset := ethchannel.NewAsset(big.NewInt(1337), common.Address{})
+	alloc := channel.NewAllocation(2, []wallet.BackendID{ethBackend}, asset)
+	alloc.SetBalance(0, asset, big.NewInt(bal0))
+	alloc.SetBalance(1, asset, big.NewInt(bal1))
+	state := &channel.State{
+		ID:         c.params.ID(),
+		Version:    version,
+		App:        channel.NoApp(),
+		Allocation: *alloc,
+		Data:       channel.NoData(),
+	}
+	if signers == nil {
+		signers = c.accounts
+	}
+	sigs := make([]wallet.Sig, len(signers))
+	for i, acc := range signers {
+		sig, err := channel.Sign(acc, state, ethBackend)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		sigs[i] = sig
+	}
+	return channel.SignedState{Params: c.params, State: state, Sigs: sigs}
+}
+
+// record appends the states to a new store and loads the history.
+func (c *testChannel) record(states ...channel.SignedState) *History {
+	c.t.Helper()
+	s, err := NewStore(c.t.TempDir())
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for _, state := range states {
+		if err := s.Append(state); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	h, err := s.Load(c.params.ID())
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return h
+}
+
+func TestHistoryVerify(t *testing.T) {
+	c := newTestChannel(t)
+	h := c.record(c.state(0, 10, 0), c.state(1, 7, 3), c.state(2, 4, 6))
+	if len(h.Entries) != 3 {
+		t.Fatalf("recorded %d states, want 3", len(h.Entries))
+	}
+	if err := h.Verify(); err != nil {
+		t.Fatalf("valid history rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(h *History)
+	}{
+		{"other channel ID", func(h *History) { h.ChannelID = "00" }},
+		{"altered summary", func(h *History) { h.Entries[1].Balances[0][0] = "8" }},
+		{"reordered states", func(h *History) { h.Entries[1], h.Entries[2] = h.Entries[2], h.Entries[1] }},
+		{"missing signature", func(h *History) { h.Entries[0].Sigs = h.Entries[0].Sigs[:1] }},
+		{"swapped signatures", func(h *History) {
+			sigs := h.Entries[2].Sigs
+			sigs[0], sigs[1] = sigs[1], sigs[0]
+		}},
+		{"state of other entry", func(h *History) { h.Entries[2].State = h.Entries[1].State }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := c.record(c.state(0, 10, 0), c.state(1, 7, 3), c.state(2, 4, 6))
+			tt.tamper(h)
+			if err := h.Verify(); err == nil {
+				t.Fatal("tampered history accepted")
+			}
+		})
+	}
+}
+
+func TestHistoryVerifyForeignSignature(t *testing.T) {
+	c := newTestChannel(t)
+	other := newTestChannel(t)
+	h := c.record(c.state(0, 10, 0, c.accounts[0], other.accounts[1]))
+	if err := h.Verify(); err == nil {
+		t.Fatal("state signed by a non-participant accepted")
+	}
+}
+
+func TestStoreIgnoresOldStates(t *testing.T) {
+	c := newTestChannel(t)
+	h := c.record(c.state(0, 10, 0), c.state(2, 4, 6), c.state(1, 7, 3), c.state(2, 4, 6))
+	if len(h.Entries) != 2 || h.Entries[1].Version != 2 {
+		t.Fatalf("recorded %d states, want versions 0 and 2", len(h.Entries))
+	}
+}
+
+func TestBundleVerify(t *testing.T) {
+	c := newTestChannel(t)
+	h := c.record(c.state(0, 10, 0), c.state(1, 7, 3))
+	key := mustKey(t)
+	b, err := NewBundle(key, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Verify(); err != nil {
+		t.Fatalf("valid bundle rejected: %v", err)
+	}
+
+	b.Signer = crypto.PubkeyToAddress(mustKey(t).PublicKey)
+	if err := b.Verify(); err == nil {
+		t.Fatal("bundle with other signer accepted")
+	}
+}
+
+func mustKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package htlc implements a go-perun channel app for conditional payments:
+// amounts locked to the SHA-256 hash of a preimage until a timeout, which the
+// receiver can claim by revealing the preimage before the timeout. Otherwise
+// the lock is released back to the sender.
+//
+// Locked amounts stay in the sender's balance, which must always cover all
+// locks of the sender. The transitions of the app are:
+//   - Lock: the sender appends a lock that has not timed out yet, balances
+//     are unchanged.
+//   - Claim: before the timeout, a lock is removed, the preimage of its hash
+//     is revealed in the data and the amount moves from the sender to the
+//     receiver.
+//   - Release: a lock is removed without revealing its preimage, balances are
+//     unchanged. The receiver may release a lock at any time, the sender once
+//     its timeout has passed.
+//   - Transfer: locks are unchanged, only the actor's balance may decrease.
+//
+// A final state must not have pending locks. The Go app checks the timeouts
+// against the wall clock of each participant when validating off-chain
+// updates, so updates close to a timeout may be rejected by a peer whose
+// clock differs. The on-chain verifier is called as a pure function and
+// cannot read the time: it accepts claims regardless of the timeout and only
+// releases by the receiver. If a lock is still pending when the channel is
+// disputed, it stays in the sender's balance.
+//
+// Only channels on Ethereum are supported. go-perun does not support apps in
+// channels over several ledgers and the Solana backend has no app support,
+// so cross-chain conditional payments are out of scope.
+package htlc
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	ethwallet "github.com/perun-network/perun-eth-backend/wallet"
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+)
+
+// Lock is an amount of an asset locked by the sender for the receiver.
+type Lock struct {
+	Hashlock [32]byte // SHA-256 hash of the preimage.
+	Sender   uint16
+	Receiver uint16
+	Asset    uint16
+	Amount   *big.Int
+	Timeout  uint64 // Unix time after which the lock cannot be claimed and the sender may release it.
+}
+
+// Hash returns the hashlock of a preimage.
+func Hash(preimage [32]byte) [32]byte {
+	return sha256.Sum256(preimage[:])
+}
+
+func (l Lock) equal(m Lock) bool {
+	return l.Hashlock == m.Hashlock && l.Sender == m.Sender && l.Receiver == m.Receiver &&
+		l.Asset == m.Asset && l.Amount.Cmp(m.Amount) == 0 && l.Timeout == m.Timeout
+}
+
+// Data is the app data of an HTLC channel. It is ABI encoded as
+// (Lock[], bytes32), so that the on-chain verifier can decode it.
+type Data struct {
+	Locks    []Lock
+	Preimage [32]byte // Preimage revealed by the transition that claimed a lock.
+}
+
+// dataArgs is the ABI encoding of Data.
+var dataArgs = func() abi.Arguments {
+	locks, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "hashlock", Type: "bytes32"},
+		{Name: "sender", Type: "uint16"},
+		{Name: "receiver", Type: "uint16"},
+		{Name: "asset", Type: "uint16"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "timeout", Type: "uint64"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	preimage, err := abi.NewType("bytes32", "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return abi.Arguments{{Name: "locks", Type: locks}, {Name: "preimage", Type: preimage}}
+}()
+
+// MarshalBinary implements channel.Data.
+func (d *Data) MarshalBinary() ([]byte, error) {
+	locks := d.Locks
+	if locks == nil {
+		locks = []Lock{}
+	}
+	return dataArgs.Pack(locks, d.Preimage)
+}
+
+// UnmarshalBinary implements channel.Data.
+func (d *Data) UnmarshalBinary(data []byte) error {
+	values, err := dataArgs.Unpack(data)
+	if err != nil {
+		return errors.WithMessage(err, "decoding HTLC data")
+	}
+	var decoded struct {
+		Locks    []Lock
+		Preimage [32]byte
+	}
+	if err := dataArgs.Copy(&decoded, values); err != nil {
+		return errors.WithMessage(err, "decoding HTLC data")
+	}
+	d.Locks, d.Preimage = decoded.Locks, decoded.Preimage
+	return nil
+}
+
+// Clone implements channel.Data.
+func (d *Data) Clone() channel.Data {
+	if d == nil {
+		return nil
+	}
+	clone := &Data{Locks: make([]Lock, len(d.Locks)), Preimage: d.Preimage}
+	for i, l := range d.Locks {
+		l.Amount = new(big.Int).Set(l.Amount)
+		clone.Locks[i] = l
+	}
+	return clone
+}
+
+// App is the HTLC app. Its definition is the address of the on-chain
+// verifier on Ethereum.
+type App struct {
+	def channel.AppID
+	now func() time.Time // Clock the timeouts of locks are checked against.
+}
+
+var _ channel.StateApp = (*App)(nil)
+
+// NewApp creates the app defined by the verifier at the given address.
+func NewApp(verifier common.Address) *App {
+	return &App{def: &ethchannel.AppID{Address: ethwallet.AsWalletAddr(verifier)}, now: time.Now}
+}
+
+// Register creates the app and registers it with go-perun, so that channels
+// proposed with it can be resolved.
+func Register(verifier common.Address) *App {
+	app := NewApp(verifier)
+	channel.RegisterApp(app)
+	return app
+}
+
+// Def implements channel.App.
+func (a *App) Def() channel.AppID {
+	return a.def
+}
+
+// NewData implements channel.App.
+func (a *App) NewData() channel.Data {
+	return new(Data)
+}
+
+// ValidInit implements channel.StateApp. The initial state has no locks.
+func (a *App) ValidInit(_ *channel.Params, state *channel.State) error {
+	data, ok := state.Data.(*Data)
+	if !ok {
+		return fmt.Errorf("invalid data type: %T", state.Data)
+	}
+	if len(data.Locks) != 0 {
+		return channel.NewStateTransitionError(state.ID, "initial state with locks")
+	}
+	return nil
+}
+
+// ValidTransition implements channel.StateApp.
+func (a *App) ValidTransition(params *channel.Params, from, to *channel.State, actor channel.Index) error {
+	fromData, ok := from.Data.(*Data)
+	if !ok {
+		return fmt.Errorf("invalid data type: %T", from.Data)
+	}
+	toData, ok := to.Data.(*Data)
+	if !ok {
+		return fmt.Errorf("invalid data type: %T", to.Data)
+	}
+	fail := func(format string, args ...interface{}) error {
+		return channel.NewStateTransitionError(to.ID, fmt.Sprintf(format, args...))
+	}
+	if to.IsFinal && len(toData.Locks) > 0 {
+		return fail("final state with pending locks")
+	}
+
+	switch len(toData.Locks) {
+	case len(fromData.Locks) + 1:
+		// Lock.
+		n := len(fromData.Locks)
+		if removedIndex(toData.Locks, fromData.Locks) != n {
+			return fail("existing locks changed")
+		}
+		l := toData.Locks[n]
+		switch {
+		case l.Sender != uint16(actor):
+			return fail("lock not created by its sender")
+		case l.Receiver == l.Sender || int(l.Receiver) >= len(params.Parts):
+			return fail("invalid lock receiver %d", l.Receiver)
+		case int(l.Asset) >= len(to.Assets):
+			return fail("invalid lock asset %d", l.Asset)
+		case l.Amount == nil || l.Amount.Sign() <= 0:
+			return fail("invalid lock amount")
+		case a.now().Unix() > int64(l.Timeout):
+			return fail("lock after its timeout")
+		case !from.Balances.Equal(to.Balances):
+			return fail("balances changed by lock")
+		}
+
+	case len(fromData.Locks) - 1:
+		// Claim or release.
+		i := removedIndex(fromData.Locks, toData.Locks)
+		if i < 0 {
+			return fail("locks changed")
+		}
+		l := fromData.Locks[i]
+		expired := a.now().Unix() > int64(l.Timeout)
+		if Hash(toData.Preimage) == l.Hashlock {
+			if expired {
+				return fail("claim after the timeout")
+			}
+			expected := from.Balances.Clone()
+			expected[l.Asset][l.Sender].Sub(expected[l.Asset][l.Sender], l.Amount)
+			expected[l.Asset][l.Receiver].Add(expected[l.Asset][l.Receiver], l.Amount)
+			if !expected.Equal(to.Balances) {
+				return fail("claim does not pay the locked amount")
+			}
+			break
+		}
+		switch {
+		case l.Receiver != uint16(actor) && (l.Sender != uint16(actor) || !expired):
+			return fail("release by other than the receiver before the timeout")
+		case !from.Balances.Equal(to.Balances):
+			return fail("balances changed by release")
+		}
+
+	case len(fromData.Locks):
+		// Transfer.
+		if !locksEqual(fromData.Locks, toData.Locks) {
+			return fail("locks changed by transfer")
+		}
+		for a := range to.Balances {
+			for p := range to.Balances[a] {
+				if channel.Index(p) != actor && to.Balances[a][p].Cmp(from.Balances[a][p]) < 0 {
+					return fail("balance of participant %d decreased", p)
+				}
+			}
+		}
+
+	default:
+		return fail("more than one lock changed")
+	}
+
+	return checkCovered(to.Balances, toData.Locks, fail)
+}
+
+// removedIndex returns the index of the lock of a that is missing in b, if b
+// is a with a single lock removed, and -1 otherwise.
+func removedIndex(a, b []Lock) int {
+	if len(a) != len(b)+1 {
+		return -1
+	}
+	i := 0
+	for i < len(b) && a[i].equal(b[i]) {
+		i++
+	}
+	for j := i; j < len(b); j++ {
+		if !a[j+1].equal(b[j]) {
+			return -1
+		}
+	}
+	return i
+}
+
+func locksEqual(a, b []Lock) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCovered checks that the balance of each sender covers its locks.
+func checkCovered(bals channel.Balances, locks []Lock, fail func(string, ...interface{}) error) error {
+	locked := make(map[[2]uint16]*big.Int)
+	for _, l := range locks {
+		key := [2]uint16{l.Asset, l.Sender}
+		if locked[key] == nil {
+			locked[key] = new(big.Int)
+		}
+		locked[key].Add(locked[key], l.Amount)
+	}
+	for key, sum := range locked {
+		if bals[key[0]][key[1]].Cmp(sum) < 0 {
+			return fail("locks of participant %d exceed its balance of asset %d", key[1], key[0])
+		}
+	}
+	return nil
+}
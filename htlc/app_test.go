@@ -0,0 +1,105 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package htlc
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+func TestValidTransition(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	app := &App{now: func() time.Time { return now }}
+	params := &channel.Params{Parts: make([]map[wallet.BackendID]wallet.Address, 2)}
+
+	preimage := [32]byte{1}
+	lock := func(timeout time.Time) Lock {
+		return Lock{
+			Hashlock: Hash(preimage),
+			Sender:   0,
+			Receiver: 1,
+			Amount:   big.NewInt(3),
+			Timeout:  uint64(timeout.Unix()),
+		}
+	}
+	pending := lock(now.Add(time.Hour))
+	expired := lock(now.Add(-time.Hour))
+	state := func(bal0, bal1 int64, preimage [32]byte, locks ...Lock) *channel.State {
+		return &channel.State{
+			Allocation: channel.Allocation{
+				Assets:   make([]channel.Asset, 1),
+				Balances: channel.Balances{{big.NewInt(bal0), big.NewInt(bal1)}},
+			},
+			Data: &Data{Locks: locks, Preimage: preimage},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		from, to *channel.State
+		actor    channel.Index
+		valid    bool
+	}{
+		{"lock", state(10, 0, [32]byte{}), state(10, 0, [32]byte{}, pending), 0, true},
+		{"lock by receiver", state(10, 0, [32]byte{}), state(10, 0, [32]byte{}, pending), 1, false},
+		{"lock after timeout", state(10, 0, [32]byte{}), state(10, 0, [32]byte{}, expired), 0, false},
+		{"lock exceeding balance", state(2, 0, [32]byte{}), state(2, 0, [32]byte{}, pending), 0, false},
+		{"lock changing balances", state(10, 0, [32]byte{}), state(9, 1, [32]byte{}, pending), 0, false},
+		{"claim", state(10, 0, [32]byte{}, pending), state(7, 3, preimage), 1, true},
+		{"claim paying too little", state(10, 0, [32]byte{}, pending), state(8, 2, preimage), 1, false},
+		{"claim after timeout", state(10, 0, [32]byte{}, expired), state(7, 3, preimage), 1, false},
+		{"release by receiver", state(10, 0, [32]byte{}, pending), state(10, 0, [32]byte{}), 1, true},
+		{"release by receiver after timeout", state(10, 0, [32]byte{}, expired), state(10, 0, [32]byte{}), 1, true},
+		{"release by sender before timeout", state(10, 0, [32]byte{}, pending), state(10, 0, [32]byte{}), 0, false},
+		{"refund by sender after timeout", state(10, 0, [32]byte{}, expired), state(10, 0, [32]byte{}), 0, true},
+		{"release changing balances", state(10, 0, [32]byte{}, expired), state(7, 3, [32]byte{}), 0, false},
+		{"transfer", state(10, 0, [32]byte{}, pending), state(6, 4, [32]byte{}, pending), 0, true},
+		{"transfer from peer", state(10, 4, [32]byte{}, pending), state(11, 3, [32]byte{}, pending), 0, false},
+		{"transfer of locked amount", state(10, 0, [32]byte{}, pending), state(2, 8, [32]byte{}, pending), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.ValidTransition(params, tt.from, tt.to, tt.actor)
+			if tt.valid && err != nil {
+				t.Fatalf("transition rejected: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("transition accepted")
+			}
+		})
+	}
+}
+
+func TestValidTransitionFinal(t *testing.T) {
+	app := &App{now: time.Now}
+	params := &channel.Params{Parts: make([]map[wallet.BackendID]wallet.Address, 2)}
+	lock := Lock{Hashlock: Hash([32]byte{1}), Receiver: 1, Amount: big.NewInt(1), Timeout: uint64(time.Now().Add(time.Hour).Unix())}
+	from := &channel.State{
+		Allocation: channel.Allocation{
+			Assets:   make([]channel.Asset, 1),
+			Balances: channel.Balances{{big.NewInt(1), big.NewInt(0)}},
+		},
+		Data: &Data{Locks: []Lock{lock}},
+	}
+	to := from.Clone()
+	to.IsFinal = true
+	if err := app.ValidTransition(params, from, to, 0); err == nil {
+		t.Fatal("final state with pending locks accepted")
+	}
+}
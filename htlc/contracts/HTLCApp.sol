@@ -0,0 +1,181 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// SPDX-License-Identifier: Apache-2.0
+
+pragma solidity ^0.8.15;
+
+/// Channel mirrors the types of the Perun adjudicator that are passed to apps.
+library Channel {
+    struct Params {
+        uint256 challengeDuration;
+        uint256 nonce;
+        Participant[] participants;
+        address app;
+        bool ledgerChannel;
+        bool virtualChannel;
+    }
+
+    struct Participant {
+        address ethAddress;
+        bytes ccAddress;
+    }
+
+    struct State {
+        bytes32 channelID;
+        uint64 version;
+        Allocation outcome;
+        bytes appData;
+        bool isFinal;
+    }
+
+    struct Allocation {
+        Asset[] assets;
+        uint256[] backends;
+        uint256[][] balances;
+        SubAlloc[] locked;
+    }
+
+    struct Asset {
+        uint256 chainID;
+        address ethHolder;
+        bytes ccHolder;
+    }
+
+    struct SubAlloc {
+        bytes32 ID;
+        uint256[] balances;
+        uint16[] indexMap;
+    }
+}
+
+/// HTLCApp verifies the transitions of the HTLC app of package htlc when a
+/// channel is progressed on-chain, with the rules of the Go app except for
+/// timeouts. The adjudicator calls apps as pure functions, so timeouts are not
+/// enforced: a lock is removed by a claim with its preimage at any time or
+/// released by its receiver,
+/// and a lock still pending when the channel concludes stays with the sender.
+contract HTLCApp {
+    struct Lock {
+        bytes32 hashlock;
+        uint16 sender;
+        uint16 receiver;
+        uint16 asset;
+        uint256 amount;
+        uint64 timeout;
+    }
+
+    function validTransition(
+        Channel.Params calldata params,
+        Channel.State calldata from,
+        Channel.State calldata to,
+        uint256 actorIdx
+    ) external pure {
+        (Lock[] memory fromLocks, ) = abi.decode(from.appData, (Lock[], bytes32));
+        (Lock[] memory toLocks, bytes32 preimage) = abi.decode(to.appData, (Lock[], bytes32));
+        require(!to.isFinal || toLocks.length == 0, "final state with pending locks");
+
+        if (toLocks.length == fromLocks.length + 1) {
+            // Lock.
+            require(removedIndex(toLocks, fromLocks) == fromLocks.length, "existing locks changed");
+            Lock memory l = toLocks[fromLocks.length];
+            require(l.sender == actorIdx, "lock not created by its sender");
+            require(l.receiver != l.sender && l.receiver < params.participants.length, "invalid lock receiver");
+            require(l.asset < to.outcome.assets.length, "invalid lock asset");
+            require(l.amount > 0, "invalid lock amount");
+            requireBalancesEqual(from.outcome.balances, to.outcome.balances, 0, 0, 0, 0);
+        } else if (toLocks.length + 1 == fromLocks.length) {
+            // Claim or release.
+            Lock memory l = fromLocks[removedIndex(fromLocks, toLocks)];
+            if (sha256(abi.encodePacked(preimage)) == l.hashlock) {
+                requireBalancesEqual(from.outcome.balances, to.outcome.balances, l.asset, l.sender, l.receiver, l.amount);
+            } else {
+                require(l.receiver == actorIdx, "release by other than the receiver");
+                requireBalancesEqual(from.outcome.balances, to.outcome.balances, 0, 0, 0, 0);
+            }
+        } else {
+            // Transfer.
+            require(toLocks.length == fromLocks.length, "more than one lock changed");
+            for (uint256 i = 0; i < toLocks.length; i++) {
+                require(lockEqual(fromLocks[i], toLocks[i]), "locks changed by transfer");
+            }
+            for (uint256 a = 0; a < to.outcome.balances.length; a++) {
+                for (uint256 p = 0; p < to.outcome.balances[a].length; p++) {
+                    require(p == actorIdx || to.outcome.balances[a][p] >= from.outcome.balances[a][p],
+                        "balance of other participant decreased");
+                }
+            }
+        }
+
+        requireCovered(to.outcome.balances, toLocks);
+    }
+
+    /// removedIndex returns the index of the lock of a that is missing in b
+    /// and reverts if b is not a with a single lock removed.
+    function removedIndex(Lock[] memory a, Lock[] memory b) internal pure returns (uint256) {
+        require(a.length == b.length + 1, "locks changed");
+        uint256 i = 0;
+        while (i < b.length && lockEqual(a[i], b[i])) {
+            i++;
+        }
+        for (uint256 j = i; j < b.length; j++) {
+            require(lockEqual(a[j + 1], b[j]), "locks changed");
+        }
+        return i;
+    }
+
+    function lockEqual(Lock memory a, Lock memory b) internal pure returns (bool) {
+        return a.hashlock == b.hashlock && a.sender == b.sender && a.receiver == b.receiver &&
+            a.asset == b.asset && a.amount == b.amount && a.timeout == b.timeout;
+    }
+
+    /// requireBalancesEqual requires that b equals a after moving amount of
+    /// the asset from sender to receiver.
+    function requireBalancesEqual(
+        uint256[][] calldata a,
+        uint256[][] calldata b,
+        uint16 asset,
+        uint16 sender,
+        uint16 receiver,
+        uint256 amount
+    ) internal pure {
+        require(a.length == b.length, "assets changed");
+        for (uint256 i = 0; i < a.length; i++) {
+            require(a[i].length == b[i].length, "participants changed");
+            for (uint256 p = 0; p < a[i].length; p++) {
+                uint256 expected = a[i][p];
+                if (i == asset && p == sender) {
+                    expected -= amount;
+                } else if (i == asset && p == receiver) {
+                    expected += amount;
+                }
+                require(b[i][p] == expected, "invalid balances");
+            }
+        }
+    }
+
+    /// requireCovered requires that the balance of each sender covers its
+    /// locks.
+    function requireCovered(uint256[][] calldata bals, Lock[] memory locks) internal pure {
+        for (uint256 i = 0; i < locks.length; i++) {
+            uint256 sum = 0;
+            for (uint256 j = 0; j < locks.length; j++) {
+                if (locks[j].asset == locks[i].asset && locks[j].sender == locks[i].sender) {
+                    sum += locks[j].amount;
+                }
+            }
+            require(bals[locks[i].asset][locks[i].sender] >= sum, "locks exceed balance");
+        }
+    }
+}
@@ -0,0 +1,45 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package htlc
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethchannel "github.com/perun-network/perun-eth-backend/channel"
+	"github.com/pkg/errors"
+)
+
+const deployGasLimit = 2_000_000
+
+// Deploy deploys the on-chain verifier of the app. The bytecode is the
+// output of `make htlc`, which compiles contracts/HTLCApp.sol.
+func Deploy(ctx context.Context, cb ethchannel.ContractBackend, deployer accounts.Account, bytecode []byte) (common.Address, error) {
+	auth, err := cb.NewTransactor(ctx, deployGasLimit, deployer)
+	if err != nil {
+		return common.Address{}, errors.WithMessage(err, "creating transactor")
+	}
+	addr, tx, _, err := bind.DeployContract(auth, abi.ABI{}, bytecode, cb)
+	if err != nil {
+		return common.Address{}, errors.WithMessage(err, "deploying HTLC app")
+	}
+	if _, err := bind.WaitDeployed(ctx, cb, tx); err != nil {
+		return common.Address{}, errors.WithMessage(err, "waiting for HTLC app deployment")
+	}
+	return addr, nil
+}
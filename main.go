@@ -16,13 +16,30 @@
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
 	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	ethwallet "github.com/perun-network/perun-eth-backend/wallet"
 	"perun.network/go-perun/wire"
+	"perun.network/sol-eth-cross-chain-demo/chaos"
+	"perun.network/sol-eth-cross-chain-demo/client"
 	"perun.network/sol-eth-cross-chain-demo/eth"
+	"perun.network/sol-eth-cross-chain-demo/oracle"
 	"perun.network/sol-eth-cross-chain-demo/solana"
+	"perun.network/sol-eth-cross-chain-demo/watchtower"
+	"perun.network/sol-eth-cross-chain-demo/webhook"
 )
 
 const (
@@ -34,12 +51,54 @@ const (
 	keyBob      = "f63d7d8e930bccd74e93cf5662fde2c28fd8be95edb70c73f1bdd863d07f412e"
 
 	PerunAddress = "GQtQCW4dREybk2FR1gabaSb89CFxGrNS74JX5fZ97Qmh"
+
+	webhookOutboxDir = "webhook-outbox"
+	historyDir       = "channel-history"
+	channelDBDir     = "channel-db"
+	auditBundleFile  = "audit-bundle.json"
+
+	// swapSlippage is the tolerated shortfall of a swap's value.
+	swapSlippage = 0.01
+
+	// demoETHPrice is the price of one ETH in SOL without a price feed.
+	demoETHPrice = 20
+
+	// simulatedGasPrice is the gas price in wei of simulations, ganache's
+	// default.
+	simulatedGasPrice = 2_000_000_000
 )
 
 func main() {
 	// Configure log flags: date/time and file/line number
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	// Parse the keys of Alice and Bob.
+	kAlice, err := crypto.HexToECDSA(keyAlice)
+	if err != nil {
+		panic(err)
+	}
+	kBob, err := crypto.HexToECDSA(keyBob)
+	if err != nil {
+		panic(err)
+	}
+
+	// Value swaps with a price feed, given as URL or file path, or at a
+	// fixed demo price. Peers only accept swaps they can value.
+	var feed oracle.Oracle = oracle.Static{"ETH/SOL": big.NewRat(demoETHPrice, 1)}
+	if src := os.Getenv("PRICE_FEED"); strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		feed = oracle.NewHTTP(src)
+	} else if src != "" {
+		feed = oracle.File{Path: src}
+	}
+	prices := client.WithPriceOracle(feed, swapSlippage)
+
+	// Optionally only show what the demo would do on-chain, without deploying
+	// contracts, connecting to the nodes or sending anything.
+	if os.Getenv("SIMULATE") != "" {
+		simulate(kAlice, kBob, prices)
+		return
+	}
+
 	// Deploy contracts.
 	log.Println("Deploying contracts.")
 
@@ -50,35 +109,153 @@ func main() {
 	asset := *ethwallet.AsWalletAddr(assetHolder)
 
 	// Setup clients.
-	kAlice, err := crypto.HexToECDSA(keyAlice)
-	if err != nil {
-		panic(err)
-	}
-	kBob, err := crypto.HexToECDSA(keyBob)
-	if err != nil {
-		panic(err)
-	}
-
 	setup, err := solana.NewExampleSetup([]string{keyAlice, keyBob}, [][20]byte{crypto.PubkeyToAddress(kAlice.PublicKey), crypto.PubkeyToAddress(kBob.PublicKey)})
 	if err != nil {
 		log.Fatalf("Failed to create Solana setup: %v", err)
 	}
 
-	bus := wire.NewLocalBus() // Message bus used for off-chain communication.
+	var bus wire.Bus = wire.NewLocalBus() // Message bus used for off-chain communication.
+
+	// Optionally inject reproducible network faults.
+	if seed := os.Getenv("CHAOS_SEED"); seed != "" {
+		n, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid CHAOS_SEED: %v", err)
+		}
+		bus = chaos.New(bus, chaos.Config{
+			Seed:      n,
+			Duplicate: 0.1,
+			Reorder:   0.1,
+			MaxDelay:  500 * time.Millisecond,
+			Log:       true,
+		})
+	}
+
+	// Check Solana deposits on the node of the Solana setup.
+	finality := client.WithFinality(client.FinalityConfig{SolanaRPC: setup.RPCURL})
+
+	// Give up funding after a while and reclaim one-sided deposits.
+	fundingTimeouts := client.WithFundingTimeouts(client.FundingTimeouts{Ethereum: 5 * time.Minute, Solana: 5 * time.Minute})
 
 	alice := eth.SetupPaymentClient(bus, chainURL, adjudicator, asset, kAlice,
-		setup.Wallets[0], setup.Accs[0], setup.Asset, setup.Funders[0], setup.Adjs[0])
+		setup.Wallets[0], setup.Accs[0], setup.Asset, setup.Funders[0], setup.Adjs[0],
+		client.WithHistory(filepath.Join(historyDir, "alice")), client.WithPersistence(filepath.Join(channelDBDir, "alice")),
+		finality, fundingTimeouts, prices)
 
 	bob := eth.SetupPaymentClient(bus, chainURL, adjudicator, asset, kBob,
-		setup.Wallets[1], setup.Accs[1], setup.Asset, setup.Funders[1], setup.Adjs[1])
+		setup.Wallets[1], setup.Accs[1], setup.Asset, setup.Funders[1], setup.Adjs[1],
+		client.WithHistory(filepath.Join(historyDir, "bob")), client.WithPersistence(filepath.Join(channelDBDir, "bob")),
+		finality, fundingTimeouts, prices)
+
+	// Optionally notify a webhook receiver about channel events.
+	if url := os.Getenv("WEBHOOK_URL"); url != "" {
+		hooks, err := webhook.NewDispatcher(webhook.Config{
+			Endpoints: []webhook.Endpoint{{URL: url, Secret: []byte(os.Getenv("WEBHOOK_SECRET"))}},
+			OutboxDir: webhookOutboxDir,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create webhook dispatcher: %v", err)
+		}
+		defer hooks.Close()
+		alice.SetWebhooks(hooks)
+		bob.SetWebhooks(hooks)
+	}
+
+	// Keep all open channels in the channel database when interrupted. Cross-
+	// chain channels cannot be settled, since the Solana backend does not
+	// implement withdrawing yet.
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		log.Println("Shutting down, persisting open channels.")
+		cfg := client.ShutdownConfig{Mode: client.ShutdownPersist}
+		log.Println("Alice:", alice.GracefulShutdown(cfg))
+		log.Println("Bob:", bob.GracefulShutdown(cfg))
+		os.Exit(1)
+	}()
+
+	// Quote the value of Alice's deposit before proposing the channel.
+	quoteCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	worth, err := alice.Quote(quoteCtx, 1, 6, client.EthToWei(big.NewFloat(1)))
+	cancel()
+	if err != nil {
+		log.Printf("Failed to quote ETH/SOL: %v", err)
+	} else {
+		log.Printf("Alice's 1 ETH is worth %v lamports, Bob deposits 50 lamports.", worth)
+	}
 
 	// Open channel, transact, close.
 	log.Println("Opening channel and depositing funds.")
-	alice.OpenChannel(bob.WireAddress(), 1, 50)
+	ch := alice.OpenChannel(bob.WireAddress(), 1, 50)
 	bob.AcceptedChannel()
 
+	// Optionally let a remote watchtower watch Alice's channels.
+	if url := os.Getenv("WATCHTOWER_URL"); url != "" {
+		tower := watchtower.NewClient(url, os.Getenv("WATCHTOWER_TOKEN"))
+		if err := alice.DelegateWatching(context.Background(), tower); err != nil {
+			log.Printf("Failed to register with watchtower: %v", err)
+		}
+	}
+
 	log.Println("Perun channel opened and funded successfully.")
+
+	// Report the Solana fees paid by the fee payer.
+	if setup.Fees != nil {
+		lamports, txs, err := setup.Fees.ChannelFees(setup.PerunAddress, ch.GetChannel().ID())
+		if err != nil {
+			log.Printf("Failed to compute channel fees: %v", err)
+		} else {
+			log.Printf("Fee payer paid %d lamports for %d Solana transactions of the channel.", lamports, txs)
+		}
+	}
+	// Export Alice's signed audit bundle of the channel.
+	if bundle, err := alice.AuditBundle(kAlice, ch.GetChannel().ID()); err != nil {
+		log.Printf("Failed to create audit bundle: %v", err)
+	} else if data, err := json.MarshalIndent(bundle, "", "  "); err != nil {
+		log.Printf("Failed to encode audit bundle: %v", err)
+	} else if err := os.WriteFile(auditBundleFile, data, 0o600); err != nil {
+		log.Printf("Failed to write audit bundle: %v", err)
+	}
+
 	// Cleanup.
 	alice.Shutdown()
 	bob.Shutdown()
 }
+
+// simulate logs the outcome of opening the demo channel, swapping and settling
+// it, checked against Bob's policy. The clients use placeholder contract and
+// Solana addresses and ganache's default gas price.
+func simulate(kAlice, kBob *ecdsa.PrivateKey, prices client.Option) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	setup, err := solana.NewSimulationSetup([]string{keyAlice, keyBob}, [][20]byte{crypto.PubkeyToAddress(kAlice.PublicKey), crypto.PubkeyToAddress(kBob.PublicKey)})
+	if err != nil {
+		log.Fatalf("Failed to create Solana setup: %v", err)
+	}
+	gas := client.DefaultGasConfig()
+	gas.Strategy = client.FixedGasPrice{GasPrice: big.NewInt(simulatedGasPrice)}
+	asset := *ethwallet.AsWalletAddr(common.Address{})
+	alice := eth.SetupSimulationClient(asset, kAlice, setup.Accs[0], setup.Asset, client.WithGasConfig(gas), prices)
+	bob := eth.SetupSimulationClient(asset, kBob, setup.Accs[1], setup.Asset, client.WithGasConfig(gas), prices)
+
+	open, err := alice.SimulateOpenChannel(ctx, bob.WireAddress(), 1, 50, bob.Policy())
+	if err != nil {
+		log.Printf("Failed to simulate opening: %v", err)
+		return
+	}
+	swap, err := alice.SimulateSwap(open.State, 0, bob.Policy())
+	if err != nil {
+		log.Printf("Failed to simulate swap: %v", err)
+		return
+	}
+	settle, err := alice.SimulateSettle(ctx, swap.State, 0, true)
+	if err != nil {
+		log.Printf("Failed to simulate settlement: %v", err)
+		return
+	}
+	for _, sim := range []*client.Simulation{open, swap, settle} {
+		log.Print(sim)
+	}
+}
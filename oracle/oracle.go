@@ -0,0 +1,143 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oracle provides exchange rates between the currencies of the
+// demo, from static configuration, a local file or an HTTP feed.
+//
+// Files and HTTP feeds are JSON objects that map pairs "BASE/QUOTE" to the
+// price of one BASE in QUOTE, as a number or a decimal string:
+//
+//	{"ETH/SOL": "18.25"}
+//
+// The price of the inverse pair is derived if only one direction is given.
+package oracle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultRequestTimeout = 10 * time.Second
+
+// Oracle provides exchange rates.
+type Oracle interface {
+	// Price returns the price of one whole unit of base in whole units of
+	// quote, e.g. the SOL one ETH is worth.
+	Price(ctx context.Context, base, quote string) (*big.Rat, error)
+}
+
+// Static is an oracle with fixed prices, keyed by "BASE/QUOTE".
+type Static map[string]*big.Rat
+
+// Price implements Oracle.
+func (s Static) Price(_ context.Context, base, quote string) (*big.Rat, error) {
+	return lookup(s, base, quote)
+}
+
+// File is an oracle that reads its prices from a local JSON file on each
+// request, so that the file can be updated by an external feed.
+type File struct {
+	Path string
+}
+
+// Price implements Oracle.
+func (f File) Price(_ context.Context, base, quote string) (*big.Rat, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "opening price file")
+	}
+	defer file.Close()
+	prices, err := decodePrices(file)
+	if err != nil {
+		return nil, err
+	}
+	return lookup(prices, base, quote)
+}
+
+// HTTP is an oracle that fetches its prices from a JSON feed.
+type HTTP struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewHTTP creates an oracle for the feed at the given URL.
+func NewHTTP(url string) *HTTP {
+	return &HTTP{url: url, httpClient: &http.Client{Timeout: defaultRequestTimeout}}
+}
+
+// Price implements Oracle.
+func (h *HTTP) Price(ctx context.Context, base, quote string) (*big.Rat, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating request")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "fetching prices")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("price feed responded with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	prices, err := decodePrices(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return lookup(prices, base, quote)
+}
+
+// decodePrices decodes a JSON price object.
+func decodePrices(r io.Reader) (map[string]*big.Rat, error) {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.WithMessage(err, "decoding prices")
+	}
+	prices := make(map[string]*big.Rat, len(raw))
+	for pair, v := range raw {
+		s := strings.Trim(string(v), `"`)
+		price, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid price of %s: %s", pair, v)
+		}
+		prices[pair] = price
+	}
+	return prices, nil
+}
+
+// lookup returns the price of the pair, or the inverse of the price of the
+// inverse pair.
+func lookup(prices map[string]*big.Rat, base, quote string) (*big.Rat, error) {
+	if base == quote {
+		return big.NewRat(1, 1), nil
+	}
+	if price, ok := prices[base+"/"+quote]; ok && price.Sign() > 0 {
+		return new(big.Rat).Set(price), nil
+	}
+	if price, ok := prices[quote+"/"+base]; ok && price.Sign() > 0 {
+		return new(big.Rat).Inv(price), nil
+	}
+	return nil, fmt.Errorf("no price for %s/%s", base, quote)
+}
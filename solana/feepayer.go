@@ -0,0 +1,131 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solana
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	solclient "github.com/perun-network/perun-solana-backend/client"
+	pchannel "perun.network/go-perun/channel"
+)
+
+const (
+	FeePayerPrivateKeyPath = "solana/scripts/accounts/fee_payer.json"
+
+	feeLookupTimeout = 30 * time.Second
+)
+
+// FeeRecord is the fee paid for a single transaction.
+type FeeRecord struct {
+	Signature solana.Signature
+	Lamports  uint64
+	Accounts  solana.PublicKeySlice // Accounts referenced by the transaction.
+}
+
+// FeeLedger records the fees paid by the fee payer.
+type FeeLedger struct {
+	mu      sync.Mutex
+	records []FeeRecord
+}
+
+// NewFeeLedger creates an empty fee ledger.
+func NewFeeLedger() *FeeLedger {
+	return &FeeLedger{}
+}
+
+// Record adds the fee of a transaction to the ledger.
+func (l *FeeLedger) Record(r FeeRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, r)
+}
+
+// Records returns all recorded transaction fees.
+func (l *FeeLedger) Records() []FeeRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]FeeRecord(nil), l.records...)
+}
+
+// Total returns the total fees paid and the number of transactions.
+func (l *FeeLedger) Total() (lamports uint64, txs int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, r := range l.records {
+		lamports += r.Lamports
+	}
+	return lamports, len(l.records)
+}
+
+// ChannelFees returns the fees paid for transactions of the given channel,
+// i.e., transactions touching its account in the Perun program.
+func (l *FeeLedger) ChannelFees(perunAddr solana.PublicKey, id pchannel.ID) (lamports uint64, txs int, err error) {
+	pda, err := solclient.ChannelPDA(id, perunAddr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("deriving channel account: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, r := range l.records {
+		if r.Accounts.Contains(pda) {
+			lamports += r.Lamports
+			txs++
+		}
+	}
+	return lamports, txs, nil
+}
+
+// WithFeePayer makes the sender pay the fees of all transactions from the
+// given sponsor account, so that the participants' accounts only need to hold
+// the channel funds. The fees of confirmed transactions are recorded in the
+// ledger if it is not nil.
+func (s *PrioritySender) WithFeePayer(feePayer solana.PrivateKey, ledger *FeeLedger) *PrioritySender {
+	s.feePayer = feePayer.PublicKey()
+	s.keys[s.feePayer] = &feePayer
+	s.ledger = ledger
+	return s
+}
+
+// recordFee looks up the fee of a confirmed transaction and adds it to the
+// ledger.
+func (s *PrioritySender) recordFee(ctx context.Context, sig solana.Signature, tx *solana.Transaction) {
+	if s.ledger == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, feeLookupTimeout)
+	defer cancel()
+	version := uint64(0)
+	res, err := s.rpcClient.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &version,
+	})
+	if err != nil || res == nil || res.Meta == nil {
+		log.Printf("Could not look up fee of transaction %v: %v", sig, err)
+		return
+	}
+	s.ledger.Record(FeeRecord{
+		Signature: sig,
+		Lamports:  res.Meta.Fee,
+		Accounts:  append(solana.PublicKeySlice(nil), tx.Message.AccountKeys...),
+	})
+}
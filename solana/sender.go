@@ -0,0 +1,302 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+	confirm "github.com/gagliardetto/solana-go/rpc/sendAndConfirmTransaction"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+
+	solclient "github.com/perun-network/perun-solana-backend/client"
+)
+
+const (
+	defaultConfirmTimeout = time.Minute
+	defaultFeeBumpPercent = 50
+	expiryPollInterval    = 2 * time.Second
+)
+
+// FeePolicy configures the compute budget and priority fee of the
+// transactions sent to the Perun program.
+type FeePolicy struct {
+	ComputeUnitLimit uint32 // Compute units requested per transaction, 0 for the cluster default.
+	MicroLamports    uint64 // Fixed priority fee per compute unit.
+	Dynamic          bool   // Estimate the fee from recent prioritization fees.
+	Percentile       int    // Percentile of the recent fees used by the estimation.
+	MinMicroLamports uint64 // Lower bound of the priority fee.
+	MaxMicroLamports uint64 // Upper bound of the priority fee, 0 for none.
+
+	Retries        int           // Resubmissions after the blockhash expired.
+	BumpPercent    uint64        // Fee increase per resubmission.
+	ConfirmTimeout time.Duration // Time to wait for a confirmation per attempt.
+}
+
+// PrioritySender is a Sender for the Solana contract backend that prepends
+// compute budget instructions to each transaction and resubmits it with a
+// fresh blockhash and a higher fee if it expires before confirmation. Since
+// the transaction is modified, it is signed again with the sender's keys.
+type PrioritySender struct {
+	rpcClient *rpc.Client
+	policy    FeePolicy
+	keys      map[solana.PublicKey]*solana.PrivateKey
+	feePayer  solana.PublicKey // Sponsor paying all fees, zero for the original payer.
+	ledger    *FeeLedger
+}
+
+var _ solclient.Sender = (*PrioritySender)(nil)
+
+// NewPrioritySender creates a sender applying the given fee policy. The keys
+// must include all signers of the transactions that are sent.
+func NewPrioritySender(rpcClient *rpc.Client, policy FeePolicy, keys ...solana.PrivateKey) *PrioritySender {
+	if policy.ConfirmTimeout == 0 {
+		policy.ConfirmTimeout = defaultConfirmTimeout
+	}
+	if policy.BumpPercent == 0 {
+		policy.BumpPercent = defaultFeeBumpPercent
+	}
+	km := make(map[solana.PublicKey]*solana.PrivateKey, len(keys))
+	for i := range keys {
+		km[keys[i].PublicKey()] = &keys[i]
+	}
+	return &PrioritySender{rpcClient: rpcClient, policy: policy, keys: km}
+}
+
+// SetRPCClient implements Sender.
+func (s *PrioritySender) SetRPCClient(rpcClient *rpc.Client) error {
+	if rpcClient == nil {
+		return errors.New("RPC client cannot be nil")
+	}
+	s.rpcClient = rpcClient
+	return nil
+}
+
+// GetRPCClient implements Sender.
+func (s *PrioritySender) GetRPCClient() *rpc.Client {
+	return s.rpcClient
+}
+
+// SendTx implements Sender. It sends the transaction once with the current
+// priority fee.
+func (s *PrioritySender) SendTx(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	prepared, _, err := s.prepare(ctx, tx, 0)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	return s.rpcClient.SendTransaction(ctx, prepared)
+}
+
+// SendAndConfirmTx implements Sender. If the transaction is not confirmed
+// before its blockhash expires, it is resubmitted with a higher fee.
+func (s *PrioritySender) SendAndConfirmTx(ctx context.Context, tx *solana.Transaction, wsClient *ws.Client) (solana.Signature, error) {
+	for attempt := 0; ; attempt++ {
+		prepared, lastValid, err := s.prepare(ctx, tx, attempt)
+		if err != nil {
+			return solana.Signature{}, err
+		}
+
+		timeout := s.policy.ConfirmTimeout
+		sig, err := confirm.SendAndConfirmTransactionWithTimeout(ctx, s.rpcClient, wsClient, prepared, timeout)
+		if err == nil {
+			s.recordFee(ctx, sig, prepared)
+			return sig, nil
+		}
+		if !isExpiryError(err) {
+			return sig, err
+		}
+
+		// The transaction may still land until its blockhash expires. Only
+		// resubmit once that is impossible, to never execute it twice.
+		landed, err := s.awaitExpiry(ctx, sig, lastValid)
+		if err != nil {
+			return sig, err
+		}
+		if landed {
+			s.recordFee(ctx, sig, prepared)
+			return sig, nil
+		}
+		if attempt >= s.policy.Retries {
+			return sig, fmt.Errorf("transaction %v expired after %d attempts", sig, attempt+1)
+		}
+		log.Printf("Solana transaction %v expired, resubmitting with higher priority fee", sig)
+	}
+}
+
+// prepare rebuilds the transaction with compute budget instructions and a
+// fresh blockhash and signs it. It returns the last block height at which the
+// transaction is valid.
+func (s *PrioritySender) prepare(ctx context.Context, tx *solana.Transaction, attempt int) (*solana.Transaction, uint64, error) {
+	instructions, err := programInstructions(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	price, err := s.price(ctx, tx, attempt)
+	if err != nil {
+		return nil, 0, err
+	}
+	var budget []solana.Instruction
+	if s.policy.ComputeUnitLimit > 0 {
+		budget = append(budget, computebudget.NewSetComputeUnitLimitInstruction(s.policy.ComputeUnitLimit).Build())
+	}
+	if price > 0 {
+		budget = append(budget, computebudget.NewSetComputeUnitPriceInstruction(price).Build())
+	}
+
+	recent, err := s.rpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, 0, fmt.Errorf("getting latest blockhash: %w", err)
+	}
+	payer := tx.Message.AccountKeys[0]
+	if !s.feePayer.IsZero() {
+		payer = s.feePayer
+	}
+	prepared, err := solana.NewTransaction(
+		append(budget, instructions...),
+		recent.Value.Blockhash,
+		solana.TransactionPayer(payer),
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("creating transaction: %w", err)
+	}
+	if _, err := prepared.Sign(func(key solana.PublicKey) *solana.PrivateKey { return s.keys[key] }); err != nil {
+		return nil, 0, fmt.Errorf("signing transaction: %w", err)
+	}
+	return prepared, recent.Value.LastValidBlockHeight, nil
+}
+
+// price returns the priority fee in micro-lamports per compute unit for the
+// given attempt.
+func (s *PrioritySender) price(ctx context.Context, tx *solana.Transaction, attempt int) (uint64, error) {
+	price := s.policy.MicroLamports
+	if s.policy.Dynamic {
+		estimate, err := s.estimate(ctx, tx)
+		if err != nil {
+			return 0, err
+		}
+		if estimate > price {
+			price = estimate
+		}
+	}
+	if price < s.policy.MinMicroLamports {
+		price = s.policy.MinMicroLamports
+	}
+	for i := 0; i < attempt; i++ {
+		bumped := price * (100 + s.policy.BumpPercent) / 100
+		if bumped == price {
+			bumped++
+		}
+		price = bumped
+	}
+	if s.policy.MaxMicroLamports > 0 && price > s.policy.MaxMicroLamports {
+		price = s.policy.MaxMicroLamports
+	}
+	return price, nil
+}
+
+// estimate returns the configured percentile of the prioritization fees
+// recently paid for the writable accounts of the transaction.
+func (s *PrioritySender) estimate(ctx context.Context, tx *solana.Transaction) (uint64, error) {
+	writable, err := tx.Message.Writable()
+	if err != nil {
+		return 0, fmt.Errorf("resolving writable accounts: %w", err)
+	}
+	fees, err := s.rpcClient.GetRecentPrioritizationFees(ctx, writable)
+	if err != nil {
+		return 0, fmt.Errorf("getting recent prioritization fees: %w", err)
+	}
+	if len(fees) == 0 {
+		return 0, nil
+	}
+
+	values := make([]uint64, len(fees))
+	for i, f := range fees {
+		values[i] = f.PrioritizationFee
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	idx := len(values) * s.policy.Percentile / 100
+	if idx >= len(values) {
+		idx = len(values) - 1
+	}
+	return values[idx], nil
+}
+
+// awaitExpiry waits until the block height passed the last valid height of
+// the transaction and returns whether it landed in the meantime.
+func (s *PrioritySender) awaitExpiry(ctx context.Context, sig solana.Signature, lastValid uint64) (bool, error) {
+	for {
+		statuses, err := s.rpcClient.GetSignatureStatuses(ctx, true, sig)
+		if err == nil && len(statuses.Value) > 0 && statuses.Value[0] != nil {
+			if statuses.Value[0].Err != nil {
+				return true, fmt.Errorf("transaction %v failed: %v", sig, statuses.Value[0].Err)
+			}
+			return true, nil
+		}
+
+		height, err := s.rpcClient.GetBlockHeight(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			return false, fmt.Errorf("getting block height: %w", err)
+		}
+		if height > lastValid {
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(expiryPollInterval):
+		}
+	}
+}
+
+// isExpiryError returns whether the error indicates that the transaction was
+// not confirmed in time or its blockhash is no longer valid.
+func isExpiryError(err error) bool {
+	if errors.Is(err, confirm.ErrTimeout) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "Blockhash not found") || strings.Contains(msg, "block height exceeded")
+}
+
+// programInstructions decompiles the instructions of the transaction, without
+// any compute budget instructions it already contains.
+func programInstructions(tx *solana.Transaction) ([]solana.Instruction, error) {
+	instructions := make([]solana.Instruction, 0, len(tx.Message.Instructions))
+	for _, ci := range tx.Message.Instructions {
+		programID, err := tx.Message.Program(ci.ProgramIDIndex)
+		if err != nil {
+			return nil, fmt.Errorf("resolving program: %w", err)
+		}
+		if programID.Equals(solana.ComputeBudget) {
+			continue
+		}
+		accounts, err := ci.ResolveInstructionAccounts(&tx.Message)
+		if err != nil {
+			return nil, fmt.Errorf("resolving accounts: %w", err)
+		}
+		instructions = append(instructions, solana.NewInstruction(programID, accounts, ci.Data))
+	}
+	return instructions, nil
+}
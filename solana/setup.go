@@ -36,114 +36,186 @@ type Setup struct {
 	Funders []*solfunder.Funder
 	Adjs    []*soladjudicator.Adjudicator
 	Asset   pchannel.Asset
+
+	PerunAddress solana.PublicKey
+	RPCURL       string     // Solana RPC endpoint of the contract backends.
+	Fees         *FeeLedger // Fees paid by the fee payer, nil without one.
+}
+
+// Participant describes a channel participant on the Solana side.
+type Participant struct {
+	Name        string            // Name used in log output.
+	KeypairPath string            // Solana keygen file, used if PrivateKey is empty.
+	PrivateKey  solana.PrivateKey // Solana private key.
+	EthKey      string            // Hex-encoded Ethereum private key.
+	CCAddress   [20]byte          // Cross-chain (Ethereum) address.
+}
+
+// SetupConfig configures the Solana setup of a set of participants.
+type SetupConfig struct {
+	RPCURL           string           // Solana RPC endpoint, defaults to localnet.
+	PerunAddress     solana.PublicKey // Perun program, read from PerunAddressPath if zero.
+	PerunAddressPath string
+	Participants     []Participant
+	FeePolicy        *FeePolicy // Priority fees of program calls, none if nil.
+
+	// FeePayer pays the fees of all Perun program transactions instead of
+	// the participants. It is read from FeePayerPath if empty; no fee payer
+	// is used if both are empty.
+	FeePayer     solana.PrivateKey
+	FeePayerPath string
 }
 
+// NewExampleSetup creates the setup for Alice and Bob of the demo.
 func NewExampleSetup(sks []string, ccaddrs [][20]byte) (*Setup, error) {
-	// Create a new RPC client:
-	client := rpc.New(rpc.LocalNet_RPC)
+	if len(sks) != 2 || len(ccaddrs) != 2 {
+		return nil, fmt.Errorf("expected keys and addresses of 2 participants, got %d and %d", len(sks), len(ccaddrs))
+	}
+	return NewSetup(SetupConfig{
+		PerunAddressPath: PerunAddressPath,
+		FeePayerPath:     FeePayerPrivateKeyPath,
+		Participants: []Participant{
+			{Name: "Alice", KeypairPath: AlicePrivateKeyPath, EthKey: sks[0], CCAddress: ccaddrs[0]},
+			{Name: "Bob", KeypairPath: BobPrivateKeyPath, EthKey: sks[1], CCAddress: ccaddrs[1]},
+		},
+	})
+}
 
-	// Parse the keypair of Alice and Bob:
-	alicePrivateKey, err := solana.PrivateKeyFromSolanaKeygenFile(AlicePrivateKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse Alice's private key: %w", err)
+// NewSimulationSetup creates only the accounts and the asset of Alice and Bob,
+// without reading keypair files or connecting to a node. The Solana addresses
+// of the accounts are placeholders.
+func NewSimulationSetup(sks []string, ccaddrs [][20]byte) (*Setup, error) {
+	if len(sks) != 2 || len(ccaddrs) != 2 {
+		return nil, fmt.Errorf("expected keys and addresses of 2 participants, got %d and %d", len(sks), len(ccaddrs))
 	}
-	bobPrivateKey, err := solana.PrivateKeyFromSolanaKeygenFile(BobPrivateKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse Bob's private key: %w", err)
+	setup := &Setup{Asset: channel.NewSOLSolanaCrossAsset()}
+	for i := range sks {
+		acc, err := solwallet.NewAccount(sks[i], solana.PublicKey{}, ccaddrs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create account %d: %w", i, err)
+		}
+		setup.Accs = append(setup.Accs, acc)
 	}
+	return setup, nil
+}
 
-	perunAddress, err := readProgramIDFromFile(PerunAddressPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read Perun address from file: %w", err)
+// NewSetup creates wallets, contract backends, funders and adjudicators for
+// each of the configured participants. The i-th entry of each list belongs to
+// the i-th participant.
+func NewSetup(cfg SetupConfig) (*Setup, error) {
+	if cfg.RPCURL == "" {
+		cfg.RPCURL = rpc.LocalNet_RPC
 	}
 
-	// Print the public keys of Alice and Bob:
-	fmt.Printf("Alice Public Key: %s\n", alicePrivateKey.PublicKey())
-	fmt.Printf("Bob Public Key: %s\n", bobPrivateKey.PublicKey())
+	// Create a new RPC client:
+	client := rpc.New(cfg.RPCURL)
+
+	perunAddress := cfg.PerunAddress
+	if perunAddress.IsZero() {
+		var err error
+		perunAddress, err = readProgramIDFromFile(cfg.PerunAddressPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Perun address from file: %w", err)
+		}
+	}
 	fmt.Printf("Perun Address: %s\n", perunAddress)
 
-	// Fetch balances
-	aliceBalanceResp, err := client.GetBalance(
-		context.TODO(),
-		alicePrivateKey.PublicKey(),
-		rpc.CommitmentFinalized, // Use finalized commitment to ensure the balance is up-to-date
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get Alice's balance: %w", err)
+	// Create SOLAsset
+	solAsset := channel.NewSOLSolanaCrossAsset()
+
+	setup := &Setup{Asset: solAsset, PerunAddress: perunAddress, RPCURL: cfg.RPCURL}
+	feePayer := cfg.FeePayer
+	if len(feePayer) == 0 && cfg.FeePayerPath != "" {
+		var err error
+		feePayer, err = solana.PrivateKeyFromSolanaKeygenFile(cfg.FeePayerPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fee payer's private key: %w", err)
+		}
+	}
+	if len(feePayer) > 0 {
+		fmt.Printf("Fee Payer Public Key: %s\n", feePayer.PublicKey())
+		setup.Fees = NewFeeLedger()
+	}
+	for i, p := range cfg.Participants {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("participant %d", i)
+		}
+		if err := setup.addParticipant(client, cfg, perunAddress, feePayer, p); err != nil {
+			return nil, err
+		}
 	}
-	fmt.Printf("Alice SOL Balance: %d lamports\n", aliceBalanceResp.Value)
+	return setup, nil
+}
+
+// addParticipant creates the wallet, contract backend, funder and adjudicator
+// of a participant and appends them to the setup.
+func (s *Setup) addParticipant(client *rpc.Client, cfg SetupConfig, perunAddress solana.PublicKey, feePayer solana.PrivateKey, p Participant) error {
+	// Parse the keypair of the participant:
+	privateKey := p.PrivateKey
+	if len(privateKey) == 0 {
+		var err error
+		privateKey, err = solana.PrivateKeyFromSolanaKeygenFile(p.KeypairPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s's private key: %w", p.Name, err)
+		}
+	}
+	fmt.Printf("%s Public Key: %s\n", p.Name, privateKey.PublicKey())
 
-	bobBalanceResp, err := client.GetBalance(
+	// Fetch balance
+	balanceResp, err := client.GetBalance(
 		context.TODO(),
-		bobPrivateKey.PublicKey(),
-		rpc.CommitmentFinalized, // Use finalized commitment to ensure the balance is up-to
+		privateKey.PublicKey(),
+		rpc.CommitmentFinalized, // Use finalized commitment to ensure the balance is up-to-date
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Bob's balance: %w", err)
+		return fmt.Errorf("failed to get %s's balance: %w", p.Name, err)
 	}
-	fmt.Printf("Bob SOL Balance: %d lamports\n", bobBalanceResp.Value)
+	fmt.Printf("%s SOL Balance: %d lamports\n", p.Name, balanceResp.Value)
 
-	// Create SOLAsset
-	solAsset := channel.NewSOLSolanaCrossAsset()
-
-	// Create wallets for Alice and Bob
-	aliceWallet := solwallet.NewEphemeralWallet()
-	aliceAcc, err := solwallet.NewAccount(sks[0], alicePrivateKey.PublicKey(), ccaddrs[0])
+	// Create wallet
+	w := solwallet.NewEphemeralWallet()
+	acc, err := solwallet.NewAccount(p.EthKey, privateKey.PublicKey(), p.CCAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Alice's account: %w", err)
+		return fmt.Errorf("failed to create %s's account: %w", p.Name, err)
 	}
-	err = aliceWallet.AddAccount(aliceAcc)
+	err = w.AddAccount(acc)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add Alice's account to wallet: %w", err)
+		return fmt.Errorf("failed to add %s's account to wallet: %w", p.Name, err)
 	}
 
-	bobWallet := solwallet.NewEphemeralWallet()
-	bobAcc, err := solwallet.NewAccount(sks[1], bobPrivateKey.PublicKey(), ccaddrs[1])
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Bob's account: %w", err)
+	// Create contract backend
+	var sender solclient.Sender = solclient.NewTxSender(rpc.New(cfg.RPCURL))
+	if cfg.FeePolicy != nil || len(feePayer) > 0 {
+		var policy FeePolicy
+		if cfg.FeePolicy != nil {
+			policy = *cfg.FeePolicy
+		}
+		ps := NewPrioritySender(rpc.New(cfg.RPCURL), policy, privateKey)
+		if len(feePayer) > 0 {
+			ps.WithFeePayer(feePayer, s.Fees)
+		}
+		sender = ps
 	}
-	err = bobWallet.AddAccount(bobAcc)
-	if err != nil {
-		return nil, fmt.Errorf("failed to add Bob's account to wallet: %w", err)
-	}
-
-	// Create contract backends for Alice and Bob
-	aliceScfg := solclient.NewSignerConfig(
-		&alicePrivateKey,
-		aliceAcc.Participant(),
-		aliceAcc,
-		solclient.NewTxSender(rpc.New(rpc.LocalNet_RPC)),
-		rpc.LocalNet_RPC, // Use the localnet RPC URL
-	)
-	aliceCB := solclient.NewContractBackend(*aliceScfg, 6)
-
-	bobScfg := solclient.NewSignerConfig(
-		&bobPrivateKey,
-		bobAcc.Participant(),
-		bobAcc,
-		solclient.NewTxSender(rpc.New(rpc.LocalNet_RPC)),
-		rpc.LocalNet_RPC, // Use the localnet RPC URL
+	scfg := solclient.NewSignerConfig(
+		&privateKey,
+		acc.Participant(),
+		acc,
+		sender,
+		cfg.RPCURL,
 	)
-	bobCB := solclient.NewContractBackend(*bobScfg, 6)
+	cb := solclient.NewContractBackend(*scfg, 6)
 
-	// Create funders for Alice and Bob
+	// Create funder and adjudicator
 	solAddr := solana.PublicKey{}
-	aliceFunder := solfunder.NewFunder(aliceCB, perunAddress, []solana.PublicKey{solAddr})
-	bobFunder := solfunder.NewFunder(bobCB, perunAddress, []solana.PublicKey{solAddr})
-
-	// Create adjudicators for Alice and Bob
-	aliceAdj := soladjudicator.NewAdjudicator()
-	bobAdj := soladjudicator.NewAdjudicator()
-
-	return &Setup{
-		Accs:    []*solwallet.Account{aliceAcc, bobAcc},
-		Wallets: []*solwallet.EphemeralWallet{aliceWallet, bobWallet},
-		Cbs:     []*solclient.ContractBackend{aliceCB, bobCB},
-		Funders: []*solfunder.Funder{aliceFunder, bobFunder},
-		Adjs:    []*soladjudicator.Adjudicator{aliceAdj, bobAdj},
-
-		Asset: solAsset,
-	}, nil
+	funder := solfunder.NewFunder(cb, perunAddress, []solana.PublicKey{solAddr})
+	adj := soladjudicator.NewAdjudicator()
+
+	s.Accs = append(s.Accs, acc)
+	s.Wallets = append(s.Wallets, w)
+	s.Cbs = append(s.Cbs, cb)
+	s.Funders = append(s.Funders, funder)
+	s.Adjs = append(s.Adjs, adj)
+	return nil
 }
 
 func readProgramIDFromFile(path string) (solana.PublicKey, error) {
@@ -0,0 +1,74 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+)
+
+const defaultRequestTimeout = 10 * time.Second
+
+// Client registers channels with a remote watchtower.
+type Client struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the watchtower at the given base URL.
+func NewClient(url, token string) *Client {
+	return &Client{
+		url:        strings.TrimSuffix(url, "/") + WatchPath,
+		token:      token,
+		httpClient: &http.Client{Timeout: defaultRequestTimeout},
+	}
+}
+
+// Watch sends the latest signed state of a channel to the watchtower.
+func (c *Client) Watch(ctx context.Context, state channel.SignedState) error {
+	var body bytes.Buffer
+	if err := encodeState(&body, state); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &body)
+	if err != nil {
+		return errors.WithMessage(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.WithMessage(err, "sending state")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("watchtower responded with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"context"
+	"crypto/subtle"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/watcher"
+	"perun.network/go-perun/watcher/local"
+)
+
+const publishTimeout = 30 * time.Second
+
+// Server watches the registered channels on all ledgers of its adjudicator.
+// When an outdated state is registered, the watcher refutes it with the
+// latest state it received.
+type Server struct {
+	watcher watcher.Watcher
+	token   string
+
+	mu       sync.Mutex
+	channels map[channel.ID]*watched
+}
+
+// watched is a channel watched by the server.
+type watched struct {
+	pub     watcher.StatesPub
+	version uint64
+}
+
+// NewServer creates a watchtower using the given adjudicator, which must
+// support all ledgers of the watched channels. If token is not empty,
+// requests must carry it as bearer token.
+func NewServer(adj channel.Adjudicator, token string) (*Server, error) {
+	w, err := local.NewWatcher(adj)
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating watcher")
+	}
+	return &Server{
+		watcher:  w,
+		token:    token,
+		channels: make(map[channel.ID]*watched),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != WatchPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	state, err := decodeState(io.LimitReader(r.Body, maxRequestSize))
+	if err == nil {
+		err = verifyState(state)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.Watch(r.Context(), state); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Watch starts watching the channel or updates its latest state. Older
+// states than the latest one are ignored.
+func (s *Server) Watch(ctx context.Context, state channel.SignedState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := state.State.ID
+	if ch, ok := s.channels[id]; ok {
+		if state.State.Version <= ch.version {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+		tx := channel.Transaction{State: state.State, Sigs: state.Sigs}
+		if err := ch.pub.Publish(ctx, tx); err != nil {
+			return errors.WithMessage(err, "publishing state")
+		}
+		ch.version = state.State.Version
+		log.Printf("Watchtower: channel %x updated to version %d", id, ch.version)
+		return nil
+	}
+
+	pub, sub, err := s.watcher.StartWatchingLedgerChannel(context.Background(), state)
+	if err != nil {
+		return errors.WithMessage(err, "starting to watch channel")
+	}
+	s.channels[id] = &watched{pub: pub, version: state.State.Version}
+	log.Printf("Watchtower: watching channel %x at version %d", id, state.State.Version)
+	go s.handleEvents(id, sub)
+	return nil
+}
+
+// handleEvents logs the adjudicator events of a channel and stops watching it
+// once it is concluded.
+func (s *Server) handleEvents(id channel.ID, sub watcher.AdjudicatorSub) {
+	for e := range sub.EventStream() {
+		log.Printf("Watchtower: channel %x: %T at version %d", id, e, e.Version())
+		if _, ok := e.(*channel.ConcludedEvent); ok {
+			s.stop(id)
+			return
+		}
+	}
+	if err := sub.Err(); err != nil {
+		log.Printf("Watchtower: watching channel %x failed: %v", id, err)
+	}
+}
+
+func (s *Server) stop(id channel.ID) {
+	s.mu.Lock()
+	delete(s.channels, id)
+	s.mu.Unlock()
+	if err := s.watcher.StopWatching(context.Background(), id); err != nil {
+		log.Printf("Watchtower: stopping to watch channel %x failed: %v", id, err)
+	}
+}
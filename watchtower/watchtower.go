@@ -0,0 +1,86 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watchtower provides a service that watches channels on behalf of
+// offline clients and refutes registrations of outdated states, and a client
+// to register channels with it.
+//
+// Channels are registered by POSTing the channel parameters followed by the
+// latest signed transaction, both in the Perun wire encoding, to WatchPath.
+package watchtower
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+)
+
+const (
+	// WatchPath is the HTTP path at which channels are registered.
+	WatchPath = "/watch"
+
+	maxRequestSize = 1 << 20
+)
+
+// encodeState writes the parameters and the signed transaction of a state.
+func encodeState(w io.Writer, state channel.SignedState) error {
+	if err := state.Params.Encode(w); err != nil {
+		return errors.WithMessage(err, "encoding params")
+	}
+	tx := channel.Transaction{State: state.State, Sigs: state.Sigs}
+	return errors.WithMessage(tx.Encode(w), "encoding transaction")
+}
+
+// decodeState reads the parameters and the signed transaction of a state.
+func decodeState(r io.Reader) (channel.SignedState, error) {
+	var params channel.Params
+	if err := params.Decode(r); err != nil {
+		return channel.SignedState{}, errors.WithMessage(err, "decoding params")
+	}
+	var tx channel.Transaction
+	if err := tx.Decode(r); err != nil {
+		return channel.SignedState{}, errors.WithMessage(err, "decoding transaction")
+	}
+	if tx.State == nil {
+		return channel.SignedState{}, errors.New("missing state")
+	}
+	return channel.SignedState{Params: &params, State: tx.State, Sigs: tx.Sigs}, nil
+}
+
+// verifyState checks that the state belongs to the channel and is signed by
+// all participants, so that it can be registered on-chain.
+func verifyState(state channel.SignedState) error {
+	if state.State.ID != state.Params.ID() {
+		return errors.New("state does not belong to channel")
+	}
+	if len(state.Sigs) != len(state.Params.Parts) {
+		return fmt.Errorf("expected %d signatures, got %d", len(state.Params.Parts), len(state.Sigs))
+	}
+	for i, part := range state.Params.Parts {
+		if state.Sigs[i] == nil {
+			return fmt.Errorf("missing signature of participant %d", i)
+		}
+		for _, addr := range part {
+			ok, err := channel.Verify(addr, state.State, state.Sigs[i])
+			if err != nil {
+				return errors.WithMessagef(err, "verifying signature of participant %d", i)
+			} else if !ok {
+				return fmt.Errorf("invalid signature of participant %d", i)
+			}
+		}
+	}
+	return nil
+}
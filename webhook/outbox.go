@@ -0,0 +1,104 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// outbox persists pending deliveries as one JSON file each. An outbox without
+// a directory keeps nothing on disk.
+type outbox struct {
+	dir string
+}
+
+func newOutbox(dir string) (*outbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+	}
+	return &outbox{dir: dir}, nil
+}
+
+// load reads all pending deliveries.
+func (o *outbox) load() ([]*delivery, error) {
+	if o.dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*delivery
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(o.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var dl delivery
+		if err := json.Unmarshal(data, &dl); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", e.Name(), err)
+		}
+		pending = append(pending, &dl)
+	}
+	return pending, nil
+}
+
+// store writes the delivery atomically, replacing a previous version.
+func (o *outbox) store(dl *delivery) error {
+	if o.dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	tmp := o.path(dl) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path(dl))
+}
+
+// remove deletes a delivery from the outbox.
+func (o *outbox) remove(dl *delivery) error {
+	if o.dir == "" {
+		return nil
+	}
+	err := os.Remove(o.path(dl))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file of a delivery. It is unique per notification and
+// endpoint.
+func (o *outbox) path(dl *delivery) string {
+	h := sha256.Sum256([]byte(dl.Endpoint))
+	name := dl.Notification.ID + "-" + hex.EncodeToString(h[:8]) + ".json"
+	return filepath.Join(o.dir, name)
+}
@@ -0,0 +1,347 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook delivers signed channel notifications to HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Perun-Signature" // HMAC-SHA256 of the timestamp and body, hex encoded.
+	TimestampHeader = "X-Perun-Timestamp" // Unix time of the delivery attempt.
+	EventHeader     = "X-Perun-Event"     // Event name of the notification.
+
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// Event names of the notifications sent by the payment client.
+const (
+	ChannelOpened        = "channel.opened"
+	PaymentReceived      = "payment.received"
+	DisputeRegistered    = "dispute.registered"
+	StaleStateRegistered = "dispute.stale_state"
+	ChannelConcluded     = "channel.concluded"
+)
+
+// Endpoint is a webhook receiver.
+type Endpoint struct {
+	URL    string
+	Secret []byte // Key used to sign the payloads.
+}
+
+// Config configures a Dispatcher.
+type Config struct {
+	Endpoints      []Endpoint
+	OutboxDir      string        // Directory of the persistent outbox.
+	MaxAttempts    int           // Delivery attempts per notification, 0 for unlimited.
+	InitialBackoff time.Duration // Delay before the first retry.
+	MaxBackoff     time.Duration // Upper bound on the delay between retries.
+	HTTPClient     *http.Client
+}
+
+// Notification is the JSON payload posted to the endpoints.
+type Notification struct {
+	ID        string                 `json:"id"`
+	Event     string                 `json:"event"`
+	Timestamp time.Time              `json:"timestamp"`
+	ChannelID string                 `json:"channel_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// delivery is a pending notification for a single endpoint.
+type delivery struct {
+	Endpoint     string       `json:"endpoint"`
+	Notification Notification `json:"notification"`
+	Attempts     int          `json:"attempts"`
+	NextAttempt  time.Time    `json:"next_attempt"`
+}
+
+// Dispatcher posts notifications to the configured endpoints. Pending
+// deliveries are kept in the outbox until they succeed, so they survive
+// restarts.
+type Dispatcher struct {
+	cfg       Config
+	secrets   map[string][]byte
+	outbox    *outbox
+	mu        sync.Mutex
+	pending   []*delivery
+	wake      chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDispatcher creates a dispatcher, loads undelivered notifications from
+// the outbox and starts delivering them.
+func NewDispatcher(cfg Config) (*Dispatcher, error) {
+	if cfg.InitialBackoff == 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultRequestTimeout}
+	}
+
+	secrets := make(map[string][]byte, len(cfg.Endpoints))
+	for _, e := range cfg.Endpoints {
+		secrets[e.URL] = e.Secret
+	}
+
+	ob, err := newOutbox(cfg.OutboxDir)
+	if err != nil {
+		return nil, fmt.Errorf("opening outbox: %w", err)
+	}
+	loaded, err := ob.load()
+	if err != nil {
+		return nil, fmt.Errorf("loading outbox: %w", err)
+	}
+	// Deliveries to endpoints that are no longer configured have no secret
+	// to be signed with.
+	var pending []*delivery
+	for _, dl := range loaded {
+		if _, ok := secrets[dl.Endpoint]; ok {
+			pending = append(pending, dl)
+			continue
+		}
+		log.Printf("Webhook: dropping notification %s to unconfigured endpoint %s", dl.Notification.ID, dl.Endpoint)
+		if err := ob.remove(dl); err != nil {
+			log.Printf("Webhook: could not remove notification: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		cfg:     cfg,
+		secrets: secrets,
+		outbox:  ob,
+		pending: pending,
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go d.run()
+	return d, nil
+}
+
+// Notify queues a notification for all endpoints.
+func (d *Dispatcher) Notify(event string, channelID string, data map[string]interface{}) {
+	n := Notification{
+		ID:        newID(),
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		ChannelID: channelID,
+		Data:      data,
+	}
+
+	d.mu.Lock()
+	for _, e := range d.cfg.Endpoints {
+		dl := &delivery{Endpoint: e.URL, Notification: n, NextAttempt: n.Timestamp}
+		if err := d.outbox.store(dl); err != nil {
+			log.Printf("Webhook: could not persist %s notification: %v", event, err)
+		}
+		d.pending = append(d.pending, dl)
+	}
+	d.mu.Unlock()
+
+	d.signal()
+}
+
+// Close stops the dispatcher. Undelivered notifications remain in the outbox.
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		d.cancel()
+		<-d.done
+	})
+}
+
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers due notifications until the dispatcher is closed.
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for {
+		for _, dl := range d.due() {
+			d.attempt(dl)
+		}
+
+		timer := time.NewTimer(d.untilNext())
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// due removes and returns all deliveries whose next attempt is due.
+func (d *Dispatcher) due() []*delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	var due, rest []*delivery
+	for _, dl := range d.pending {
+		if dl.NextAttempt.After(now) {
+			rest = append(rest, dl)
+		} else {
+			due = append(due, dl)
+		}
+	}
+	d.pending = rest
+	return due
+}
+
+// untilNext returns the time until the next delivery is due.
+func (d *Dispatcher) untilNext() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	next := d.cfg.MaxBackoff
+	for _, dl := range d.pending {
+		if until := time.Until(dl.NextAttempt); until < next {
+			next = until
+		}
+	}
+	if next < 0 {
+		next = 0
+	}
+	return next
+}
+
+// attempt tries to deliver the notification once and reschedules it with
+// exponential backoff on failure.
+func (d *Dispatcher) attempt(dl *delivery) {
+	err := d.post(dl)
+	if err == nil {
+		if err := d.outbox.remove(dl); err != nil {
+			log.Printf("Webhook: could not remove delivered notification: %v", err)
+		}
+		return
+	}
+
+	dl.Attempts++
+	if d.cfg.MaxAttempts > 0 && dl.Attempts >= d.cfg.MaxAttempts {
+		log.Printf("Webhook: giving up on %s notification %s to %s after %d attempts: %v",
+			dl.Notification.Event, dl.Notification.ID, dl.Endpoint, dl.Attempts, err)
+		if err := d.outbox.remove(dl); err != nil {
+			log.Printf("Webhook: could not remove notification: %v", err)
+		}
+		return
+	}
+
+	dl.NextAttempt = time.Now().Add(d.backoff(dl.Attempts))
+	log.Printf("Webhook: delivery of %s to %s failed (attempt %d), retrying at %v: %v",
+		dl.Notification.ID, dl.Endpoint, dl.Attempts, dl.NextAttempt.Format(time.RFC3339), err)
+	if err := d.outbox.store(dl); err != nil {
+		log.Printf("Webhook: could not persist notification: %v", err)
+	}
+
+	d.mu.Lock()
+	d.pending = append(d.pending, dl)
+	d.mu.Unlock()
+}
+
+// backoff returns the delay before the given retry.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// post sends the signed notification to its endpoint.
+func (d *Dispatcher) post(dl *delivery) error {
+	body, err := json.Marshal(dl.Notification)
+	if err != nil {
+		return fmt.Errorf("encoding notification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, defaultRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, dl.Notification.Event)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(d.secrets[dl.Endpoint], timestamp, body))
+
+	resp, err := d.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the signature header value for the given timestamp header
+// value and body. It signs timestamp + "." + body, so that a captured request
+// cannot be replayed with a new timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature header value of a received body. Receivers
+// should also reject timestamps that are too old.
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// request is a delivery received by a test endpoint.
+type request struct {
+	body      []byte
+	timestamp string
+	signature string
+	event     string
+	at        time.Time
+}
+
+// endpoint starts a test server that records the deliveries it receives and
+// answers with the status returned by status for the n-th request.
+func endpoint(t *testing.T, status func(n int) int) (*httptest.Server, <-chan request) {
+	t.Helper()
+	requests := make(chan request, 16)
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading body: %v", err)
+		}
+		requests <- request{
+			body:      body,
+			timestamp: r.Header.Get(TimestampHeader),
+			signature: r.Header.Get(SignatureHeader),
+			event:     r.Header.Get(EventHeader),
+			at:        time.Now(),
+		}
+		w.WriteHeader(status(int(n.Add(1))))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func receive(t *testing.T, requests <-chan request) request {
+	t.Helper()
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery received")
+		return request{}
+	}
+}
+
+func TestSignedDelivery(t *testing.T) {
+	secret := []byte("secret")
+	srv, requests := endpoint(t, func(int) int { return http.StatusOK })
+	d, err := NewDispatcher(Config{Endpoints: []Endpoint{{URL: srv.URL, Secret: secret}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	d.Notify(PaymentReceived, "abcd", map[string]interface{}{"amount": "1"})
+	r := receive(t, requests)
+	if !Verify(secret, r.timestamp, r.body, r.signature) {
+		t.Fatal("signature does not verify")
+	}
+	if Verify([]byte("other"), r.timestamp, r.body, r.signature) {
+		t.Error("signature verifies with another secret")
+	}
+	if Verify(secret, r.timestamp+"0", r.body, r.signature) {
+		t.Error("signature verifies with another timestamp")
+	}
+	if Verify(secret, r.timestamp, append(r.body, ' '), r.signature) {
+		t.Error("signature verifies with another body")
+	}
+
+	var n Notification
+	if err := json.Unmarshal(r.body, &n); err != nil {
+		t.Fatal(err)
+	}
+	if r.event != PaymentReceived || n.Event != PaymentReceived || n.ChannelID != "abcd" {
+		t.Errorf("got event header %q and notification %+v", r.event, n)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	srv, requests := endpoint(t, func(n int) int {
+		if n <= 2 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	dir := t.TempDir()
+	backoff := 50 * time.Millisecond
+	d, err := NewDispatcher(Config{
+		Endpoints:      []Endpoint{{URL: srv.URL, Secret: []byte("secret")}},
+		OutboxDir:      dir,
+		InitialBackoff: backoff,
+		MaxBackoff:     time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	d.Notify(ChannelOpened, "", nil)
+	first, second, third := receive(t, requests), receive(t, requests), receive(t, requests)
+	if gap := second.at.Sub(first.at); gap < backoff {
+		t.Errorf("first retry after %v, want at least %v", gap, backoff)
+	}
+	if gap := third.at.Sub(second.at); gap < 2*backoff {
+		t.Errorf("second retry after %v, want at least %v", gap, 2*backoff)
+	}
+	if string(first.body) != string(third.body) {
+		t.Error("retry changed the notification")
+	}
+
+	// The delivered notification is removed from the outbox.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox still holds %d entries", len(entries))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+	for attempts, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestOutboxReplay(t *testing.T) {
+	dir := t.TempDir()
+	secret := []byte("secret")
+
+	// Deliveries to a failing endpoint stay in the outbox after Close.
+	var healthy atomic.Bool
+	srv, requests := endpoint(t, func(int) int {
+		if healthy.Load() {
+			return http.StatusOK
+		}
+		return http.StatusServiceUnavailable
+	})
+	cfg := Config{
+		Endpoints:      []Endpoint{{URL: srv.URL, Secret: secret}},
+		OutboxDir:      dir,
+		InitialBackoff: 100 * time.Millisecond,
+	}
+	d, err := NewDispatcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Notify(ChannelConcluded, "abcd", nil)
+	failed := receive(t, requests)
+	d.Close()
+	for len(requests) > 0 {
+		<-requests
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("outbox holds %d entries (%v), want 1", len(entries), err)
+	}
+
+	// A new dispatcher on the same outbox delivers them once their retry is
+	// due.
+	healthy.Store(true)
+	d, err = NewDispatcher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	replayed := receive(t, requests)
+	var before, after Notification
+	if err := json.Unmarshal(failed.body, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(replayed.body, &after); err != nil {
+		t.Fatal(err)
+	}
+	if after.ID != before.ID || after.Event != ChannelConcluded {
+		t.Fatalf("replayed %+v, want %+v", after, before)
+	}
+	if !Verify(secret, replayed.timestamp, replayed.body, replayed.signature) {
+		t.Fatal("replayed signature does not verify")
+	}
+}
+
+func TestOutboxDropsUnconfiguredEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	ob, err := newOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl := &delivery{Endpoint: "http://removed.invalid", Notification: Notification{ID: "1", Event: ChannelOpened}}
+	if err := ob.store(dl); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDispatcher(Config{OutboxDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("outbox holds %d entries, want 0", len(entries))
+	}
+}